@@ -0,0 +1,408 @@
+// Package analysis provides a [goanalysis.Analyzer] checking the usage of the bcs package, which can be
+// run with go vet, or with the bcsvet command:
+//
+//	go vet -vettool=$(which bcsvet) ./...
+//
+// The analyzer reports at compile time the mistakes that otherwise only show up as errors when a value is
+// encoded or decoded:
+//   - types with unsupported kinds, such as maps, floats, int and uint, passed to [bcs.Marshal], [bcs.Unmarshal],
+//     [bcs.Decode] and the other functions encoding or decoding values,
+//   - non-pointers passed to [bcs.Unmarshal], [bcs.Decoder.Decode] and [bcs.FrameReader.ReadFrame],
+//   - [bcs.Enum] types with variants that are not pointers or interfaces,
+//   - bcs tags that cannot be parsed, such as misspelled options.
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strconv"
+	"strings"
+
+	goanalysis "golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+
+	"github.com/fardream/go-bcs/internal/bcstag"
+)
+
+const bcsPath = "github.com/fardream/go-bcs/bcs"
+
+// Analyzer checks the usage of the bcs package.
+var Analyzer = &goanalysis.Analyzer{
+	Name:     "bcs",
+	Doc:      "check the types encoded and decoded with bcs, and the bcs tags",
+	URL:      "https://pkg.go.dev/github.com/fardream/go-bcs/bcs/analysis",
+	Requires: []*goanalysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// direction is whether a call encodes or decodes values, which decides the customized marshalers that apply.
+type direction int
+
+const (
+	encoding direction = 1 << iota
+	decoding
+)
+
+// target is where a function of the bcs package takes the type of the values it encodes or decodes.
+type target struct {
+	dir direction
+	// args are the indices of the arguments holding the values, empty if the type is the type argument.
+	args []int
+	// pointer indicates the argument must be a pointer.
+	pointer bool
+}
+
+// targets are the functions and methods of the bcs package taking values, methods are keyed by "Type.Method".
+var targets = map[string]target{
+	"Marshal":                {dir: encoding, args: []int{0}},
+	"MustMarshal":            {dir: encoding, args: []int{0}},
+	"AppendMarshal":          {dir: encoding, args: []int{1}},
+	"MarshalTo":              {dir: encoding, args: []int{1}},
+	"MarshalWith":            {dir: encoding},
+	"Size":                   {dir: encoding, args: []int{0}},
+	"Encoder.Encode":         {dir: encoding, args: []int{0}},
+	"FrameWriter.WriteFrame": {dir: encoding, args: []int{0}},
+	"NewRaw":                 {dir: encoding},
+	"Unmarshal":              {dir: decoding, args: []int{1}, pointer: true},
+	"UnmarshalAll":           {dir: decoding, args: []int{1}, pointer: true},
+	"Decoder.Decode":         {dir: decoding, args: []int{0}, pointer: true},
+	"Decoder.DecodeSeed":     {dir: decoding, args: []int{1}, pointer: true},
+	"FrameReader.ReadFrame":  {dir: decoding, args: []int{0}, pointer: true},
+	"UnmarshalAs":            {dir: decoding},
+	"MustUnmarshal":          {dir: decoding},
+	"Decode":                 {dir: decoding},
+	"DecodeSeq":              {dir: decoding},
+	"VectorIter":             {dir: decoding},
+	"Skip":                   {dir: decoding},
+	"Extract":                {dir: decoding, args: []int{1}},
+	"Patch":                  {dir: encoding | decoding, args: []int{1, 3}},
+	"CodecFor":               {dir: encoding | decoding},
+	"IsCanonical":            {dir: encoding | decoding},
+	"Canonicalize":           {dir: encoding | decoding},
+	"Equal":                  {dir: encoding, args: []int{0, 1}},
+	"Compare":                {dir: encoding, args: []int{0, 1}},
+	"Hash":                   {dir: encoding, args: []int{0}},
+	"SortSlice":              {dir: encoding},
+}
+
+func run(pass *goanalysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	nodes := []ast.Node{(*ast.CallExpr)(nil), (*ast.StructType)(nil), (*ast.TypeSpec)(nil)}
+	insp.Preorder(nodes, func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			checkCall(pass, n)
+		case *ast.StructType:
+			checkTags(pass, n)
+		case *ast.TypeSpec:
+			checkEnum(pass, n)
+		}
+	})
+
+	return nil, nil
+}
+
+// checkCall checks the type of the values encoded or decoded by a call to the bcs package.
+func checkCall(pass *goanalysis.Pass, call *ast.CallExpr) {
+	fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != bcsPath {
+		return
+	}
+	name := fn.Name()
+	if recv := fn.Signature().Recv(); recv != nil {
+		named, ok := deref(recv.Type()).(*types.Named)
+		if !ok {
+			return
+		}
+		name = named.Obj().Name() + "." + name
+	}
+	tgt, ok := targets[name]
+	if !ok {
+		return
+	}
+
+	if len(tgt.args) == 0 {
+		inst, ok := pass.TypesInfo.Instances[calleeIdent(call.Fun)]
+		if !ok || inst.TypeArgs.Len() == 0 {
+			return
+		}
+		checkType(pass, call, fn.Pkg(), name, tgt.dir, inst.TypeArgs.At(0))
+		return
+	}
+
+	for _, i := range tgt.args {
+		if i >= len(call.Args) {
+			return
+		}
+		t := pass.TypesInfo.TypeOf(call.Args[i])
+		if t == nil || types.IsInterface(t) {
+			continue
+		}
+		if tgt.pointer {
+			ptr, ok := t.Underlying().(*types.Pointer)
+			if !ok {
+				pass.Reportf(call.Args[i].Pos(), "bcs.%s requires a pointer, got %s", name, typeString(pass, t))
+				continue
+			}
+			t = ptr.Elem()
+		}
+		checkType(pass, call, fn.Pkg(), name, tgt.dir, t)
+	}
+}
+
+// checkType reports the constructs of t which cannot be encoded or decoded by the call to the bcs package pkg.
+func checkType(pass *goanalysis.Pass, call *ast.CallExpr, pkg *types.Package, name string, dir direction, t types.Type) {
+	w := &walker{
+		pass:         pass,
+		dir:          dir,
+		marshalers:   lookupInterfaces(pkg, "MarshalerTo", "Marshaler"),
+		unmarshalers: lookupInterfaces(pkg, "UnmarshalerFrom", "Unmarshaler"),
+		visited:      make(map[types.Type]bool),
+	}
+	w.walk(t, typeString(pass, t))
+	for _, p := range w.problems {
+		pass.Reportf(call.Pos(), "bcs.%s: %s", name, p)
+	}
+}
+
+// calleeIdent returns the identifier of the function called, through the explicit instantiation and the package selector.
+func calleeIdent(fun ast.Expr) *ast.Ident {
+	for {
+		switch f := ast.Unparen(fun).(type) {
+		case *ast.IndexExpr:
+			fun = f.X
+		case *ast.IndexListExpr:
+			fun = f.X
+		case *ast.SelectorExpr:
+			return f.Sel
+		case *ast.Ident:
+			return f
+		default:
+			return nil
+		}
+	}
+}
+
+// walker walks a type to find the constructs that cannot be encoded or decoded, the same as [bcs.CheckType].
+// Interfaces are not reported, since they can hold a value when they are encoded or decoded, and the tags
+// and the enums are checked where they are declared.
+type walker struct {
+	pass *goanalysis.Pass
+	dir  direction
+	// marshalers and unmarshalers are the interfaces of the customized marshalers and unmarshalers in the bcs package.
+	marshalers   []*types.Interface
+	unmarshalers []*types.Interface
+	visited      map[types.Type]bool
+	problems     []string
+}
+
+func (w *walker) walk(t types.Type, path string) {
+	if w.visited[t] {
+		return
+	}
+	w.visited[t] = true
+
+	if w.customized(t) {
+		return
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Pointer:
+		w.walk(u.Elem(), path)
+	case *types.Slice:
+		w.walk(u.Elem(), path+"[]")
+	case *types.Array:
+		w.walk(u.Elem(), path+"[]")
+	case *types.Struct:
+		enum := types.Implements(t, enumInterface)
+		for i := 0; i < u.NumFields(); i++ {
+			f := u.Field(i)
+			if !f.Exported() {
+				continue
+			}
+			tag, _ := bcstag.Parse(reflect.StructTag(u.Tag(i)).Get(bcstag.Name))
+			if tag.Ignore {
+				continue
+			}
+			if _, ok := f.Type().Underlying().(*types.Pointer); tag.Optional && !ok {
+				continue
+			}
+			if _, ok := f.Type().Underlying().(*types.Pointer); enum && !ok {
+				continue
+			}
+			w.walk(f.Type(), path+"."+f.Name())
+		}
+	case *types.Map:
+		w.report(path, t, "maps are not supported")
+	case *types.Basic:
+		switch {
+		case u.Info()&(types.IsFloat|types.IsComplex) != 0:
+			w.report(path, t, "floating point numbers are not supported")
+		case u.Kind() == types.Int || u.Kind() == types.Uint:
+			w.report(path, t, "int and uint are not supported, use integers with explicit sizes")
+		}
+	}
+}
+
+func (w *walker) report(path string, t types.Type, message string) {
+	w.problems = append(w.problems, fmt.Sprintf("%s has unsupported type %s: %s", path, typeString(w.pass, t), message))
+}
+
+// customized checks if the values of t are encoded or decoded by customized marshalers in the direction of the walker.
+func (w *walker) customized(t types.Type) bool {
+	if w.dir&encoding != 0 && !implementsAny(t, w.marshalers) {
+		return false
+	}
+	if w.dir&decoding != 0 && !implementsAny(t, w.unmarshalers) {
+		return false
+	}
+
+	return true
+}
+
+// lookupInterfaces returns the interfaces of names declared in pkg, skipping the names which are not interfaces.
+func lookupInterfaces(pkg *types.Package, names ...string) []*types.Interface {
+	var r []*types.Interface
+	for _, name := range names {
+		obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		if iface, ok := obj.Type().Underlying().(*types.Interface); ok {
+			r = append(r, iface)
+		}
+	}
+
+	return r
+}
+
+// implementsAny checks if t or *t implements any of ifaces, the same as the bcs package finding the customized marshalers.
+func implementsAny(t types.Type, ifaces []*types.Interface) bool {
+	for _, iface := range ifaces {
+		if types.Implements(t, iface) || types.Implements(types.NewPointer(t), iface) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkTags checks the bcs tags of the fields of a struct can be parsed.
+func checkTags(pass *goanalysis.Pass, st *ast.StructType) {
+	for _, f := range st.Fields.List {
+		if f.Tag == nil {
+			continue
+		}
+		s, err := strconv.Unquote(f.Tag.Value)
+		if err != nil {
+			continue
+		}
+		value, ok := reflect.StructTag(s).Lookup(bcstag.Name)
+		if !ok {
+			continue
+		}
+		_, err = bcstag.Parse(value)
+		var unknown *bcstag.UnknownError
+		switch {
+		case err == nil:
+		case errors.As(err, &unknown) && suggest(unknown.Option) != "":
+			pass.Reportf(f.Tag.Pos(), "invalid bcs tag: %v, did you mean %s?", err, suggest(unknown.Option))
+		default:
+			pass.Reportf(f.Tag.Pos(), "invalid bcs tag: %v", err)
+		}
+	}
+}
+
+// checkEnum checks the variants of an enum declared by spec are pointers or interfaces.
+func checkEnum(pass *goanalysis.Pass, spec *ast.TypeSpec) {
+	obj, ok := pass.TypesInfo.Defs[spec.Name].(*types.TypeName)
+	if !ok || types.IsInterface(obj.Type()) || !types.Implements(obj.Type(), enumInterface) {
+		return
+	}
+	st, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		pass.Reportf(spec.Name.Pos(), "enum %s must be a struct", obj.Name())
+		return
+	}
+
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		if !f.Exported() {
+			continue
+		}
+		tag, err := bcstag.Parse(reflect.StructTag(st.Tag(i)).Get(bcstag.Name))
+		if err != nil || tag.Ignore {
+			continue
+		}
+		switch f.Type().Underlying().(type) {
+		case *types.Pointer, *types.Interface:
+		default:
+			pass.Reportf(f.Pos(), "variant %s of enum %s must be a pointer or an interface, got %s", f.Name(), obj.Name(), typeString(pass, f.Type()))
+		}
+	}
+}
+
+// enumInterface is [bcs.Enum], declared here since the enums are checked in packages which may not import bcs.
+var enumInterface = types.NewInterfaceType([]*types.Func{
+	types.NewFunc(token.NoPos, nil, "IsBcsEnum", types.NewSignatureType(nil, nil, nil, nil, nil, false)),
+}, nil).Complete()
+
+func deref(t types.Type) types.Type {
+	if ptr, ok := t.(*types.Pointer); ok {
+		return ptr.Elem()
+	}
+
+	return t
+}
+
+// typeString returns t qualified relative to the package being analyzed.
+func typeString(pass *goanalysis.Pass, t types.Type) string {
+	return types.TypeString(t, types.RelativeTo(pass.Pkg))
+}
+
+// suggest returns the known tag option closest to the misspelled option, empty if none is close.
+func suggest(option string) string {
+	key, _, hasValue := strings.Cut(option, "=")
+	candidates := bcstag.Options
+	if hasValue {
+		candidates = bcstag.Keys
+	}
+
+	best, bestDistance := "", 3
+	for _, c := range candidates {
+		if d := distance(key, c); d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+
+	return best
+}
+
+// distance is the edit distance between a and b.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+
+	return prev[len(b)]
+}
@@ -0,0 +1,115 @@
+package example
+
+import (
+	"io"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+type Account struct {
+	Name    string `bcs:"maxlen=32,utf8"`
+	Balance uint64
+	Nonce   *uint32           `bcs:"optional,since=2"`
+	Ignored map[string]string `bcs:"-"`
+	private float64
+}
+
+type Order struct {
+	Price    float64
+	Quantity int
+	Tags     []map[string]uint8
+	Account  *Account
+}
+
+type Tagged struct {
+	A uint8  `bcs:"optinal"`         // want `invalid bcs tag: unknown tag: optinal in optinal, did you mean optional\?`
+	B string `bcs:"maxln=4"`         // want `invalid bcs tag: unknown tag: maxln=4 in maxln=4, did you mean maxlen\?`
+	C uint8  `bcs:"since=0"`         // want `invalid bcs tag: invalid version: 0 in since=0, versions must be positive`
+	D uint8  `bcs:"compact"`         // want `invalid bcs tag: unknown tag: compact in compact$`
+	E uint8  `bcs:"since=3,until=2"` // want `invalid bcs tag: field is removed before it is added: since=3,until=2`
+}
+
+type Payload struct {
+	Account *Account
+	Order   *Order
+	Invalid Order // want `variant Invalid of enum Payload must be a pointer or an interface, got Order`
+	Other   any
+	Skipped uint8 `bcs:"-"`
+}
+
+func (Payload) IsBcsEnum() {}
+
+// Price has a customized marshaler, so its float is not reported when encoded. UnmarshalBCS has the wrong
+// signature and is not a bcs.Unmarshaler, so the float is reported when decoded.
+type Price struct {
+	Value float64
+}
+
+func (p Price) MarshalBCS() ([]byte, error) { return nil, nil }
+
+func (p *Price) UnmarshalBCS(data []byte) (int, error) { return 0, nil }
+
+// Amount has customized marshalers, with the methods on the pointer, so its float is not reported.
+type Amount struct {
+	Value float64
+}
+
+func (a *Amount) MarshalBCSTo(e *bcs.Encoder) error { return nil }
+
+func (a *Amount) UnmarshalBCS(r io.Reader) (int, error) { return 0, nil }
+
+// Encoded only has a customized marshaler, so it is reported when decoded.
+type Encoded struct {
+	Value float64
+}
+
+func (e Encoded) MarshalBCS() ([]byte, error) { return nil, nil }
+
+func use(data []byte, d *bcs.Decoder, e *bcs.Encoder, v any) {
+	bcs.Marshal(Account{})
+	bcs.Marshal(&Order{}) // want `bcs.Marshal: \*Order.Price has unsupported type float64: floating point numbers are not supported` `bcs.Marshal: \*Order.Quantity has unsupported type int: int and uint are not supported, use integers with explicit sizes` `bcs.Marshal: \*Order.Tags\[\] has unsupported type map\[string\]uint8: maps are not supported`
+	bcs.Marshal(Price{})
+	bcs.Marshal(Amount{})
+	bcs.Marshal(Encoded{})
+	bcs.Marshal(v)
+
+	var account Account
+	bcs.Unmarshal(data, &account)
+	bcs.Unmarshal(data, account) // want `bcs.Unmarshal requires a pointer, got Account`
+	bcs.Unmarshal(data, v)
+	d.Decode(&account)
+	d.Decode(account)  // want `bcs.Decoder.Decode requires a pointer, got Account`
+	e.Encode([]uint{}) // want `bcs.Encoder.Encode: \[\]uint\[\] has unsupported type uint: int and uint are not supported, use integers with explicit sizes`
+
+	bcs.Compare(account, []int8{})
+	bcs.Compare(account, []int{}) // want `bcs.Compare: \[\]int\[\] has unsupported type int`
+
+	bcs.UnmarshalAs[Price](data) // want `bcs.UnmarshalAs: Price.Value has unsupported type float64: floating point numbers are not supported`
+	bcs.UnmarshalAs[Amount](data)
+	bcs.UnmarshalAs[Encoded](data) // want `bcs.UnmarshalAs: Encoded.Value has unsupported type float64: floating point numbers are not supported`
+	bcs.Decode[*Payload](d)        // want `bcs.Decode: \*Payload.Order.Price has unsupported type float64` `Payload.Order.Quantity` `Payload.Order.Tags\[\]`
+
+	bcs.MarshalTo(data, account)
+	bcs.MarshalTo(data, []int{}) // want `bcs.MarshalTo: \[\]int\[\] has unsupported type int`
+	var codec bcs.Codec[[]float32]
+	bcs.MarshalWith(codec, nil) // want `bcs.MarshalWith: \[\]float32\[\] has unsupported type float32`
+	bcs.Extract(data, Account{}, "Name")
+	bcs.Extract(data, Price{}, "Value") // want `bcs.Extract: Price.Value has unsupported type float64`
+	bcs.Extract(data, v, "")
+	bcs.Patch(data, Amount{}, "Value", Amount{})
+	bcs.Patch(data, Encoded{}, "Value", 1.5) // want `bcs.Patch: Encoded.Value has unsupported type float64` `bcs.Patch: float64 has unsupported type float64`
+}
+
+func frames(w *bcs.FrameWriter, r *bcs.FrameReader) {
+	var account Account
+	w.WriteFrame(account)
+	w.WriteFrame(Order{}) // want `bcs.FrameWriter.WriteFrame: Order.Price has unsupported type float64` `Order.Quantity` `Order.Tags\[\]`
+	r.ReadFrame(&account)
+	r.ReadFrame(account)    // want `bcs.FrameReader.ReadFrame requires a pointer, got Account`
+	r.ReadFrame(&Encoded{}) // want `bcs.FrameReader.ReadFrame: Encoded.Value has unsupported type float64`
+}
+
+// Variant is an interface with IsBcsEnum, which is not an enum declaration.
+type Variant interface {
+	IsBcsEnum()
+}
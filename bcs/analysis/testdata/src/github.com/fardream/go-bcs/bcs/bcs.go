@@ -0,0 +1,71 @@
+// Package bcs is the stub of the bcs package with the functions checked by the analyzer.
+package bcs
+
+import "io"
+
+type Enum interface {
+	IsBcsEnum()
+}
+
+type Marshaler interface {
+	MarshalBCS() ([]byte, error)
+}
+
+type MarshalerTo interface {
+	MarshalBCSTo(e *Encoder) error
+}
+
+type Unmarshaler interface {
+	UnmarshalBCS(r io.Reader) (int, error)
+}
+
+type UnmarshalerFrom interface {
+	UnmarshalBCSFrom(d *Decoder) error
+}
+
+type Encoder struct{}
+
+func (e *Encoder) Encode(v any) error { return nil }
+
+type Decoder struct{}
+
+func (d *Decoder) Decode(v any) (int, error) { return 0, nil }
+
+func Marshal(v any) ([]byte, error) { return nil, nil }
+
+func MarshalTo(buf []byte, v any) (int, error) { return 0, nil }
+
+type Codec[T any] interface {
+	Encode(e *Encoder, v T) error
+	Decode(d *Decoder) (T, error)
+}
+
+func MarshalWith[T any](c Codec[T], v T) ([]byte, error) { return nil, nil }
+
+type Span struct{}
+
+func Extract(data []byte, typ any, path string) (any, Span, error) { return nil, Span{}, nil }
+
+func Patch(data []byte, typ any, path string, newValue any) ([]byte, error) { return nil, nil }
+
+type FrameWriter struct{}
+
+func (w *FrameWriter) WriteFrame(v any) error { return nil }
+
+type FrameReader struct{}
+
+func (r *FrameReader) ReadFrame(v any) error { return nil }
+
+func Unmarshal(data []byte, v any) (int, error) { return 0, nil }
+
+func UnmarshalAs[T any](data []byte) (T, error) {
+	var v T
+	return v, nil
+}
+
+func Decode[T any](d *Decoder) (T, error) {
+	var v T
+	return v, nil
+}
+
+func Compare(a, b any) (int, error) { return 0, nil }
@@ -0,0 +1,129 @@
+package bcs
+
+import (
+	"encoding/binary"
+	"io"
+	"reflect"
+	"unsafe"
+)
+
+// littleEndian indicates the host is little endian, where the memory of integers is the same as their bcs encoding.
+var littleEndian = binary.NativeEndian.Uint16([]byte{1, 0}) == 1
+
+var uint128Type = reflect.TypeFor[Uint128]()
+
+// isPlain checks if the memory of the type is exactly its bcs encoding, so a value,
+// or a slice of the values, can be copied to and from the encoded bytes as is.
+// Types with customized marshalers or unmarshalers on the type or its pointer are never plain.
+//
+//   - integers on little endian hosts, or 1 byte integers on any host.
+//     bool is not plain since only 0 and 1 are valid when decoding.
+//   - [Uint128] on little endian hosts.
+//   - arrays of plain elements.
+//   - structs without padding, whose fields are all plain, exported, not tagged, and without constraints.
+func isPlain(p *typePlan) bool {
+	if p.typ == uint128Type {
+		return littleEndian
+	}
+	if p.hasCustomMarshaler() || p.hasCustomUnmarshaler() || p.isEnum || p.err != nil {
+		return false
+	}
+	if p.validator || p.preparer || p.preparerAddr {
+		return false
+	}
+
+	switch p.kind {
+	case reflect.Int8, reflect.Uint8:
+		return true
+	case reflect.Int16, reflect.Uint16, reflect.Int32, reflect.Uint32, reflect.Int64, reflect.Uint64:
+		return littleEndian
+	case reflect.Array:
+		return p.elem.plain
+	case reflect.Struct:
+		if len(p.fields) != p.typ.NumField() {
+			return false
+		}
+		var size uintptr
+		for _, f := range p.fields {
+			if !f.plan.plain || f.tag != 0 || f.constraints != nil || f.isVersioned() {
+				return false
+			}
+			size += f.plan.typ.Size()
+		}
+		return size == p.typ.Size()
+	default:
+		return false
+	}
+}
+
+// plainBytes returns the memory of n plain values starting at ptr.
+func plainBytes(ptr unsafe.Pointer, n int, elemSize int) []byte {
+	if n == 0 {
+		return nil
+	}
+
+	return unsafe.Slice((*byte)(ptr), n*elemSize)
+}
+
+// encodePlainArray writes the memory of an array of plain type.
+func (e *Encoder) encodePlainArray(v reflect.Value, p *typePlan) error {
+	if !v.CanAddr() {
+		tmp := reflect.New(p.typ).Elem()
+		tmp.Set(v)
+		v = tmp
+	}
+
+	return e.write(plainBytes(v.Addr().UnsafePointer(), 1, int(p.typ.Size())))
+}
+
+// encodePlainSlice writes the length and the memory of a slice of plain type.
+func (e *Encoder) encodePlainSlice(v reflect.Value, p *typePlan) error {
+	if err := e.WriteLength(v.Len()); err != nil {
+		return err
+	}
+
+	return e.write(plainBytes(v.UnsafePointer(), v.Len(), int(p.elem.typ.Size())))
+}
+
+// decodePlainArray reads the memory of an array of plain type.
+func (d *Decoder) decodePlainArray(v reflect.Value, p *typePlan) error {
+	return d.readFull(plainBytes(v.Addr().UnsafePointer(), 1, int(p.typ.Size())))
+}
+
+// decodePlainSlice reads a slice of plain type of the given size.
+func (d *Decoder) decodePlainSlice(v reflect.Value, p *typePlan, size int) error {
+	elemSize := int(p.elem.typ.Size())
+
+	// in memory input, the length can be checked before allocation.
+	if d.data != nil {
+		if uint64(len(d.data)-d.offset) < uint64(size)*uint64(elemSize) {
+			return io.ErrUnexpectedEOF
+		}
+		if v.IsNil() || v.Cap() < size {
+			v.Set(reflect.MakeSlice(v.Type(), size, size))
+		} else {
+			v.SetLen(size)
+		}
+		return d.readFull(plainBytes(v.UnsafePointer(), size, elemSize))
+	}
+
+	// otherwise allocate in chunks, so a large declared length doesn't
+	// cause a large allocation before the input is confirmed to have that many bytes.
+	chunk := max(1, maxChunkSize/max(1, elemSize))
+	if v.IsNil() || v.Cap() < min(size, chunk) {
+		v.Set(reflect.MakeSlice(v.Type(), 0, min(size, chunk)))
+	} else {
+		v.SetLen(0)
+	}
+	for v.Len() < size {
+		n := v.Len()
+		k := min(size-n, chunk)
+		v.Grow(k)
+		v.SetLen(n + k)
+		if err := d.readFull(plainBytes(v.Index(n).Addr().UnsafePointer(), k, elemSize)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
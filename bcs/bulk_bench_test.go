@@ -0,0 +1,70 @@
+package bcs_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+// boxedU64 is encoded the same as uint64, but the unexported field makes it go through the element by element path.
+type boxedU64 struct {
+	V uint64
+	_ struct{}
+}
+
+func benchmarkBulk[T any](b *testing.B, newElem func(i int) T) {
+	sizes := []int{16, 256, 4096}
+
+	for _, size := range sizes {
+		data := make([]T, size)
+		for i := range data {
+			data[i] = newElem(i)
+		}
+		encoded, err := bcs.Marshal(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(fmt.Sprintf("encode_%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(encoded)))
+			for i := 0; i < b.N; i++ {
+				if _, err := bcs.Marshal(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("decode_%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(encoded)))
+			for i := 0; i < b.N; i++ {
+				var result []T
+				if _, err := bcs.Unmarshal(encoded, &result); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkBulkU64(b *testing.B) {
+	benchmarkBulk(b, func(i int) uint64 { return uint64(i) * 997 })
+}
+
+func BenchmarkBulkU64_portable(b *testing.B) {
+	benchmarkBulk(b, func(i int) boxedU64 { return boxedU64{V: uint64(i) * 997} })
+}
+
+func BenchmarkBulkU128(b *testing.B) {
+	benchmarkBulk(b, func(i int) bcs.Uint128 { return *bcs.NewUint128FromUint64(uint64(i), uint64(i)) })
+}
+
+func BenchmarkBulkAddress(b *testing.B) {
+	benchmarkBulk(b, func(i int) [32]byte { return [32]byte{byte(i), byte(i >> 8)} })
+}
+
+func BenchmarkBulkBitmap(b *testing.B) {
+	benchmarkBulk(b, func(i int) [16]uint16 { return [16]uint16{uint16(i)} })
+}
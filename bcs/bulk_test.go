@@ -0,0 +1,134 @@
+package bcs_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+type Candle struct {
+	Open  uint64
+	Close uint64
+	Flags [4]uint16
+}
+
+// Padded has padding between the fields, so it is not copied as is.
+type Padded struct {
+	A uint8
+	B uint32
+}
+
+type Bulk struct {
+	Prices    []uint64
+	Deltas    []int32
+	Amounts   []bcs.Uint128
+	Addresses [][32]byte
+	Bitmap    [3]uint16
+	Candles   []Candle
+	Padded    []Padded
+}
+
+func TestBulk(t *testing.T) {
+	v := Bulk{
+		Prices:    []uint64{1, 1 << 40},
+		Deltas:    []int32{-1, 2},
+		Amounts:   []bcs.Uint128{*bcs.NewUint128FromUint64(7, 8)},
+		Addresses: [][32]byte{{1, 2, 3}, {31: 9}},
+		Bitmap:    [3]uint16{0x0102, 0, 0xffff},
+		Candles:   []Candle{{Open: 3, Close: 4, Flags: [4]uint16{5, 6, 7, 8}}},
+		Padded:    []Padded{{A: 1, B: 2}},
+	}
+
+	var expected []byte
+	expected = append(expected, 2, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0)
+	expected = append(expected, 2, 0xff, 0xff, 0xff, 0xff, 2, 0, 0, 0)
+	expected = append(expected, 1, 7, 0, 0, 0, 0, 0, 0, 0, 8, 0, 0, 0, 0, 0, 0, 0)
+	expected = append(expected, 2, 1, 2, 3)
+	expected = append(expected, make([]byte, 29+31)...)
+	expected = append(expected, 9)
+	expected = append(expected, 2, 1, 0, 0, 0xff, 0xff)
+	expected = append(expected, 1, 3, 0, 0, 0, 0, 0, 0, 0, 4, 0, 0, 0, 0, 0, 0, 0, 5, 0, 6, 0, 7, 0, 8, 0)
+	expected = append(expected, 1, 1, 2, 0, 0, 0)
+
+	b, err := bcs.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(b, expected) {
+		t.Fatalf("want: %v\ngot:  %v", expected, b)
+	}
+
+	// the array is not addressable
+	b, err = bcs.Marshal(v.Bitmap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(b, []byte{2, 1, 0, 0, 0xff, 0xff}) {
+		t.Fatalf("unexpected array encoding: %v", b)
+	}
+
+	var fromBytes Bulk
+	if err := bcs.UnmarshalAll(expected, &fromBytes); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(bcs.MustMarshal(fromBytes), expected) {
+		t.Fatalf("decoded from bytes doesn't round trip")
+	}
+
+	var fromReader Bulk
+	if _, err := bcs.NewDecoder(bytes.NewReader(expected)).Decode(&fromReader); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(bcs.MustMarshal(fromReader), expected) {
+		t.Fatalf("decoded from reader doesn't round trip")
+	}
+}
+
+func TestBulk_truncated(t *testing.T) {
+	// declares 3 u64 but only has 2.
+	data := []byte{3, 1, 0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0}
+
+	var fromBytes []uint64
+	if _, err := bcs.Unmarshal(data, &fromBytes); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("want unexpected EOF, got: %v", err)
+	}
+
+	var fromReader []uint64
+	if _, err := bcs.NewDecoder(bytes.NewReader(data)).Decode(&fromReader); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("want unexpected EOF, got: %v", err)
+	}
+
+	// a large declared length with little input.
+	var large []uint64
+	if _, err := bcs.NewDecoder(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, 0x0f, 1})).Decode(&large); err == nil {
+		t.Fatal("want error for truncated input")
+	}
+}
+
+// Compact has the memory layout of a u64, but encodes itself with a pointer receiver as a single byte.
+type Compact struct {
+	V uint64
+}
+
+func (c *Compact) MarshalBCSTo(e *bcs.Encoder) error {
+	return e.WriteU8(uint8(c.V))
+}
+
+func TestBulk_pointerMarshaler(t *testing.T) {
+	v := struct {
+		A []Compact
+		B [1]Compact
+	}{A: []Compact{{V: 7}}, B: [1]Compact{{V: 7}}}
+
+	b, err := bcs.Marshal(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{1, 7, 7}; !bytes.Equal(b, want) {
+		t.Fatalf("want: %v\ngot:  %v", want, b)
+	}
+}
@@ -0,0 +1,48 @@
+package bcs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotCanonical is returned by [IsCanonical] when the input decodes, but is not the canonical encoding of the value.
+var ErrNotCanonical = errors.New("not canonical encoding")
+
+// IsCanonical checks data is the canonical encoding of a value of type T, which is required before
+// verifying the signatures of the bytes.
+//
+// The decoder already rejects the non-canonical encodings of the primitives, such as ULEB128 with
+// trailing zeros or bool other than 0 and 1. On top of that, data must be completely consumed like [UnmarshalAll],
+// and the decoded value must encode to exactly data, which catches the lenient customized unmarshalers.
+// The error wraps [ErrNotCanonical] if data decodes but is not canonical.
+func IsCanonical[T any](data []byte) error {
+	encoded, err := Canonicalize[T](data)
+	if err != nil {
+		return err
+	}
+	if len(encoded) != len(data) {
+		return fmt.Errorf("%w: re-encoded %d bytes, got %d bytes", ErrNotCanonical, len(encoded), len(data))
+	}
+	for i := range data {
+		if data[i] != encoded[i] {
+			return fmt.Errorf("%w: re-encoded bytes differ at offset %d", ErrNotCanonical, i)
+		}
+	}
+
+	return nil
+}
+
+// Canonicalize decodes a value of type T from data, and returns its canonical encoding.
+// data must be completely consumed like [UnmarshalAll].
+//
+// The input is decoded as strictly as [Unmarshal], so the non-canonical encodings of the primitives, such as
+// ULEB128 with trailing zeros or bool other than 0 and 1, error instead of being normalized. Only what
+// the customized unmarshalers of T accept leniently is normalized, by their marshalers.
+func Canonicalize[T any](data []byte) ([]byte, error) {
+	v, err := UnmarshalAs[T](data)
+	if err != nil {
+		return nil, err
+	}
+
+	return AppendMarshal(make([]byte, 0, len(data)), &v)
+}
@@ -0,0 +1,78 @@
+package bcs_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+// lenientFlag accepts any non zero byte as true, but always encodes true as 1.
+type lenientFlag bool
+
+func (f lenientFlag) MarshalBCS() ([]byte, error) {
+	if f {
+		return []byte{1}, nil
+	}
+	return []byte{0}, nil
+}
+
+func (f *lenientFlag) UnmarshalBCSFrom(d *bcs.Decoder) error {
+	b, err := d.ReadU8()
+	*f = b != 0
+	return err
+}
+
+type Signed struct {
+	Nonce uint64
+	Flag  lenientFlag
+	Memo  string
+}
+
+func TestIsCanonical(t *testing.T) {
+	canonical := bcs.MustMarshal(Signed{Nonce: 7, Flag: true, Memo: "hi"})
+	if err := bcs.IsCanonical[Signed](canonical); err != nil {
+		t.Fatal(err)
+	}
+
+	lenient := bytes.Clone(canonical)
+	lenient[8] = 2
+	if err := bcs.IsCanonical[Signed](lenient); !errors.Is(err, bcs.ErrNotCanonical) {
+		t.Fatalf("want ErrNotCanonical, got: %v", err)
+	}
+
+	if err := bcs.IsCanonical[Signed](append(bytes.Clone(canonical), 0)); err == nil || errors.Is(err, bcs.ErrNotCanonical) {
+		t.Fatalf("want error for trailing bytes, got: %v", err)
+	}
+
+	// the length of memo encoded as ULEB128 with a trailing zero.
+	overlong := append(bytes.Clone(canonical[:9]), 0x82, 0x00, 'h', 'i')
+	if err := bcs.IsCanonical[Signed](overlong); err == nil {
+		t.Fatal("want error for non canonical ULEB128")
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	canonical := bcs.MustMarshal(Signed{Nonce: 7, Flag: true, Memo: "hi"})
+	lenient := bytes.Clone(canonical)
+	lenient[8] = 2
+
+	got, err := bcs.Canonicalize[Signed](lenient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, canonical) {
+		t.Fatalf("want: %v\ngot:  %v", canonical, got)
+	}
+
+	if _, err := bcs.Canonicalize[Signed](canonical[:5]); err == nil {
+		t.Fatal("want error for truncated input")
+	}
+
+	// the primitives are decoded strictly, and are not normalized.
+	overlong := append(bytes.Clone(canonical[:9]), 0x82, 0x00, 'h', 'i')
+	if _, err := bcs.Canonicalize[Signed](overlong); err == nil {
+		t.Fatal("want error for non canonical ULEB128")
+	}
+}
@@ -0,0 +1,156 @@
+package bcs
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Problem is a construct in a type that cannot be encoded or decoded, reported by [CheckType].
+type Problem struct {
+	// Path is the path to the value with the problem from the checked type, such as "Orders[].Fee",
+	// where "[]" is any element of a vector or an array, and empty for the checked type itself.
+	Path string
+	// Type is the type of the value with the problem.
+	Type    reflect.Type
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Path == "" {
+		return fmt.Sprintf("%s: %s", p.Type.String(), p.Message)
+	}
+
+	return fmt.Sprintf("%s (%s): %s", p.Path, p.Type.String(), p.Message)
+}
+
+// CheckType walks type t statically and reports the constructs that fail to encode or decode, which otherwise
+// only show up when a value reaches them, such as maps, floats, enum variants that are not pointers or interfaces,
+// optional fields that are not pointers, invalid tags, and interfaces, which can only be decoded if they
+// already hold a value.
+//
+// Types implementing both a marshaler and an unmarshaler, such as [MarshalerTo] and [UnmarshalerFrom], are not
+// walked into. Each type is checked once, and the problems inside are reported at the first path it is found.
+//
+// An empty result means every value of t can be encoded and decoded, which is useful to assert in tests
+// for all the types on the wire.
+func CheckType(t reflect.Type) []Problem {
+	c := &typeChecker{visited: make(map[reflect.Type]bool)}
+	c.check(t, "")
+
+	return c.problems
+}
+
+type typeChecker struct {
+	visited  map[reflect.Type]bool
+	problems []Problem
+}
+
+func (c *typeChecker) report(t reflect.Type, path string, format string, args ...any) {
+	c.problems = append(c.problems, Problem{Path: path, Type: t, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *typeChecker) check(t reflect.Type, path string) {
+	if c.visited[t] {
+		return
+	}
+	c.visited[t] = true
+
+	p := planFor(t)
+	marshals := p.hasCustomMarshaler()
+	if marshals && p.hasCustomUnmarshaler() {
+		return
+	}
+	if p.isEnum {
+		c.checkEnum(p.enumPlan().typ, path)
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		c.check(t.Elem(), path)
+	case reflect.Slice, reflect.Array:
+		c.check(t.Elem(), path+"[]")
+	case reflect.Struct:
+		c.checkStruct(t, path)
+	case reflect.Interface:
+		c.report(t, path, "interface can only be decoded if it already holds a value")
+	case reflect.Map:
+		c.report(t, path, "maps are not supported, use a slice of key value pairs or a customized marshaler")
+	case reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		c.report(t, path, "floating point numbers are not supported")
+	case reflect.Int, reflect.Uint:
+		c.report(t, path, "int and uint are not supported, use integers with explicit sizes")
+	}
+}
+
+func (c *typeChecker) checkStruct(t reflect.Type, path string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fieldPath := joinPath(path, field.Name)
+
+		tag, err := parseTag(field.Tag.Get(tagName))
+		if err != nil {
+			c.report(field.Type, fieldPath, "%v", err)
+			continue
+		}
+		if tag.flags.isIgnored() {
+			continue
+		}
+		if tag.flags.isOptional() && field.Type.Kind() != reflect.Pointer {
+			c.report(field.Type, fieldPath, "optional field can only be pointer")
+			continue
+		}
+		if tag.constraints != nil {
+			if err := tag.constraints.compile(planFor(field.Type)); err != nil {
+				c.report(field.Type, fieldPath, "%v", err)
+			}
+		}
+
+		c.check(field.Type, fieldPath)
+	}
+}
+
+func (c *typeChecker) checkEnum(t reflect.Type, path string) {
+	if t.Kind() != reflect.Struct {
+		c.report(t, path, "only support struct for Enum, got %s", t.Kind().String())
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fieldPath := joinPath(path, field.Name)
+
+		tag, err := parseTag(field.Tag.Get(tagName))
+		if err != nil {
+			c.report(field.Type, fieldPath, "%v", err)
+			continue
+		}
+		if tag.flags.isIgnored() {
+			continue
+		}
+		if tag.since != 0 || tag.until != 0 {
+			c.report(field.Type, fieldPath, "since and until are not supported for enum variants")
+		}
+		if kind := field.Type.Kind(); kind != reflect.Pointer && kind != reflect.Interface {
+			c.report(field.Type, fieldPath, "enum only supports fields that are either pointers or interfaces, unless they are ignored")
+			continue
+		}
+
+		c.check(field.Type, fieldPath)
+	}
+}
+
+// joinPath appends the field name to the path, the same as the path of [ValidationError].
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
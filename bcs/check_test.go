@@ -0,0 +1,66 @@
+package bcs_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+type badEnum struct {
+	Value  uint8
+	Nested *badStruct
+}
+
+func (badEnum) IsBcsEnum() {}
+
+type badStruct struct {
+	Prices   map[string]uint64
+	Ratio    float64
+	Count    int
+	Memo     string `bcs:"optional"`
+	Unknown  uint8  `bcs:"whatever"`
+	Limited  uint8  `bcs:"maxlen=3"`
+	Any      any
+	Children []badStruct
+	Ignored  map[string]uint64 `bcs:"-"`
+	hidden   map[string]uint64
+}
+
+func TestCheckType(t *testing.T) {
+	problems := bcs.CheckType(reflect.TypeFor[[]badEnum]())
+
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.Path)
+	}
+	want := []string{
+		"[].Value",
+		"[].Nested.Prices",
+		"[].Nested.Ratio",
+		"[].Nested.Count",
+		"[].Nested.Memo",
+		"[].Nested.Unknown",
+		"[].Nested.Limited",
+		"[].Nested.Any",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want: %v\ngot:  %v\n%v", want, got, problems)
+	}
+	if s := problems[1].String(); s != "[].Nested.Prices (map[string]uint64): maps are not supported, use a slice of key value pairs or a customized marshaler" {
+		t.Fatalf("unexpected problem: %s", s)
+	}
+}
+
+func TestCheckType_valid(t *testing.T) {
+	for _, typ := range []reflect.Type{
+		reflect.TypeFor[Tree](),
+		reflect.TypeFor[Orders](),
+		reflect.TypeFor[Constrained](),
+		reflect.TypeFor[Account](),
+	} {
+		if problems := bcs.CheckType(typ); len(problems) != 0 {
+			t.Errorf("%s: unexpected problems: %v", typ.String(), problems)
+		}
+	}
+}
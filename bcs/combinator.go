@@ -0,0 +1,457 @@
+package bcs
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"unsafe"
+)
+
+// Codecs of the primitives of bcs. Together with the combinators such as [Vec], [StructOf] and [EnumOf],
+// they describe the layouts of values as values, similar to @mysten/bcs in typescript:
+//
+//	coin := bcs.StructOf("Coin",
+//		bcs.Field("id", bcs.FixedBytes(32)),
+//		bcs.Field("value", bcs.U64),
+//	)
+//
+// They encode and decode without reflection or struct tags, and can describe layouts only known at runtime.
+// Codecs can be nested with each other, and with the reflection based codecs from [CodecFor].
+// Use [MarshalWith] and [UnmarshalWith] to encode and decode with a codec. Like [Encoder.Encode], encoding with
+// a codec directly on an [Encoder] buffers the output, which is written to the [io.Writer] when Encode returns.
+var (
+	U8      Codec[uint8]    = codecFunc[uint8]{(*Encoder).WriteU8, (*Decoder).ReadU8}
+	U16     Codec[uint16]   = codecFunc[uint16]{(*Encoder).WriteU16, (*Decoder).ReadU16}
+	U32     Codec[uint32]   = codecFunc[uint32]{(*Encoder).WriteU32, (*Decoder).ReadU32}
+	U64     Codec[uint64]   = codecFunc[uint64]{(*Encoder).WriteU64, (*Decoder).ReadU64}
+	U128    Codec[Uint128]  = codecFunc[Uint128]{(*Encoder).WriteU128, (*Decoder).ReadU128}
+	U256    Codec[*big.Int] = codecFunc[*big.Int]{(*Encoder).WriteU256, (*Decoder).ReadU256}
+	ULEB128 Codec[uint32]   = codecFunc[uint32]{(*Encoder).WriteULEB128, (*Decoder).ReadULEB128}
+	Bool    Codec[bool]     = codecFunc[bool]{(*Encoder).WriteBool, (*Decoder).ReadBool}
+	String  Codec[string]   = codecFunc[string]{(*Encoder).WriteString, (*Decoder).ReadString}
+	// Bytes is vector<u8>.
+	Bytes Codec[[]byte] = codecFunc[[]byte]{(*Encoder).WriteBytes, (*Decoder).ReadBytes}
+	// Unit is the unit type, which is encoded as nothing. It is useful for enum variants without values.
+	Unit Codec[struct{}] = codecFunc[struct{}]{
+		func(*Encoder, struct{}) error { return nil },
+		func(*Decoder) (struct{}, error) { return struct{}{}, nil },
+	}
+)
+
+// CodecOf creates a [Codec] from a pair of functions, which is useful for codecs composed of other codecs.
+// Like the codecs of this package, the value is buffered and written to the [io.Writer] of the encoder at once,
+// and it counts as one element of a vector started by [Encoder.BeginVector], no matter how many codecs encode
+// its parts.
+func CodecOf[T any](encode func(e *Encoder, v T) error, decode func(d *Decoder) (T, error)) Codec[T] {
+	return codecFunc[T]{encode, decode}
+}
+
+// codecFunc is a [Codec] from a pair of functions.
+type codecFunc[T any] struct {
+	encode func(e *Encoder, v T) error
+	decode func(d *Decoder) (T, error)
+}
+
+// Encode encodes v, buffering the output like [Encoder.Encode] so the primitives are not written
+// to the [io.Writer] of the encoder one at a time.
+func (c codecFunc[T]) Encode(e *Encoder, v T) error {
+	if err := e.beginTop(); err != nil {
+		return err
+	}
+
+	return e.endTop(c.encode(e, v))
+}
+
+func (c codecFunc[T]) Decode(d *Decoder) (T, error) {
+	return c.decode(d)
+}
+
+// MarshalWith encodes v with codec c.
+func MarshalWith[T any](c Codec[T], v T) ([]byte, error) {
+	e := newBytesEncoder(nil)
+	if err := c.Encode(e, v); err != nil {
+		return nil, err
+	}
+
+	return e.buf, nil
+}
+
+// UnmarshalWith decodes a value from data with codec c, and errors if data is not completely consumed,
+// like [UnmarshalAll].
+func UnmarshalWith[T any](c Codec[T], data []byte) (T, error) {
+	d := NewBytesDecoder(data)
+	v, err := c.Decode(d)
+	if err != nil {
+		return v, err
+	}
+	if d.Offset() != len(data) {
+		return v, fmt.Errorf("did not unmarshal all bytes, got %d expected %d", d.Offset(), len(data))
+	}
+
+	return v, nil
+}
+
+// FixedBytes is n bytes without a length prefix, such as an address. Encoding errors if the length is not n.
+func FixedBytes(n int) Codec[[]byte] {
+	return codecFunc[[]byte]{
+		encode: func(e *Encoder, v []byte) error {
+			if len(v) != n {
+				return fmt.Errorf("expected %d bytes, got %d", n, len(v))
+			}
+			return e.WriteFixedBytes(v)
+		},
+		decode: func(d *Decoder) ([]byte, error) {
+			v := make([]byte, n)
+			return v, d.ReadFixedBytes(v)
+		},
+	}
+}
+
+// Vec is vector<T>: the length followed by the elements encoded with c.
+//
+// When decoding, the preallocation is bounded the same way as [Unmarshal], so a large declared length
+// doesn't cause a large allocation before the elements are decoded.
+func Vec[T any](c Codec[T]) Codec[[]T] {
+	return codecFunc[[]T]{
+		encode: func(e *Encoder, v []T) error {
+			if err := e.WriteLength(len(v)); err != nil {
+				return err
+			}
+			return encodeElements(e, c, v)
+		},
+		decode: func(d *Decoder) ([]T, error) {
+			size, err := d.ReadLength()
+			if err != nil {
+				return nil, err
+			}
+			return decodeElements(d, c, size)
+		},
+	}
+}
+
+// Array is a fixed length array of n elements encoded with c, without a length prefix.
+// Encoding errors if the number of elements is not n.
+func Array[T any](n int, c Codec[T]) Codec[[]T] {
+	return codecFunc[[]T]{
+		encode: func(e *Encoder, v []T) error {
+			if len(v) != n {
+				return fmt.Errorf("expected %d elements, got %d", n, len(v))
+			}
+			return encodeElements(e, c, v)
+		},
+		decode: func(d *Decoder) ([]T, error) {
+			return decodeElements(d, c, n)
+		},
+	}
+}
+
+func encodeElements[T any](e *Encoder, c Codec[T], v []T) error {
+	for i, elem := range v {
+		if err := c.Encode(e, elem); err != nil {
+			return fmt.Errorf("[%d]: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func decodeElements[T any](d *Decoder, c Codec[T], size int) ([]T, error) {
+	var zero T
+	r := make([]T, 0, min(size, max(1, maxChunkSize/max(1, int(unsafe.Sizeof(zero))))))
+	for i := 0; i < size; i++ {
+		elem, err := c.Decode(d)
+		if err != nil {
+			return nil, fmt.Errorf("[%d]: %w", i, err)
+		}
+		r = append(r, elem)
+	}
+
+	return r, nil
+}
+
+// OptionOf is Option<T>: the option tag followed by the value encoded with c if it is present,
+// which is a nil pointer if it is absent, the same as the fields tagged optional.
+func OptionOf[T any](c Codec[T]) Codec[*T] {
+	return codecFunc[*T]{
+		encode: func(e *Encoder, v *T) error {
+			if err := e.WriteOptionTag(v != nil); err != nil {
+				return err
+			}
+			if v == nil {
+				return nil
+			}
+			return c.Encode(e, *v)
+		},
+		decode: func(d *Decoder) (*T, error) {
+			isSome, err := d.ReadOptionTag()
+			if err != nil || !isSome {
+				return nil, err
+			}
+			v, err := c.Decode(d)
+			if err != nil {
+				return nil, err
+			}
+			return &v, nil
+		},
+	}
+}
+
+// Any erases the type of the values of c, so codecs of different types can be put together,
+// for example, as the elements of a vector or a tuple whose layout is known at runtime.
+// Encoding errors if the value is not of type T.
+func Any[T any](c Codec[T]) Codec[any] {
+	if ac, ok := c.(Codec[any]); ok {
+		return ac
+	}
+
+	return codecFunc[any]{
+		encode: func(e *Encoder, v any) error {
+			t, ok := v.(T)
+			if !ok {
+				return fmt.Errorf("expected %s, got %T", reflect.TypeFor[T]().String(), v)
+			}
+			return c.Encode(e, t)
+		},
+		decode: func(d *Decoder) (any, error) {
+			return c.Decode(d)
+		},
+	}
+}
+
+// Transform converts the values of c from type T to type U when decoding, and from U to T when encoding.
+// It can turn the values of [StructOf] and [EnumOf] into go types.
+func Transform[T, U any](c Codec[T], decode func(T) (U, error), encode func(U) (T, error)) Codec[U] {
+	return codecFunc[U]{
+		encode: func(e *Encoder, v U) error {
+			t, err := encode(v)
+			if err != nil {
+				return err
+			}
+			return c.Encode(e, t)
+		},
+		decode: func(d *Decoder) (U, error) {
+			t, err := c.Decode(d)
+			if err != nil {
+				var zero U
+				return zero, err
+			}
+			return decode(t)
+		},
+	}
+}
+
+// StructField is a field of [StructOf], created by [Field].
+type StructField struct {
+	name  string
+	codec Codec[any]
+}
+
+// Field creates a field of name for [StructOf], whose value is encoded with c.
+func Field[T any](name string, c Codec[T]) StructField {
+	return StructField{name: name, codec: Any(c)}
+}
+
+// StructOf is a struct of the fields, which are encoded in order. The values are maps from
+// the names of the fields to their values. Encoding errors if a field is missing, or if there is an unknown field.
+// name is used in errors. StructOf panics if the names of the fields are not unique.
+func StructOf(name string, fields ...StructField) Codec[map[string]any] {
+	names := make(map[string]struct{}, len(fields))
+	for _, f := range fields {
+		if _, ok := names[f.name]; ok {
+			panic(fmt.Sprintf("bcs: duplicate field %s in struct %s", f.name, name))
+		}
+		names[f.name] = struct{}{}
+	}
+
+	return codecFunc[map[string]any]{
+		encode: func(e *Encoder, v map[string]any) error {
+			if err := e.Enter(); err != nil {
+				return err
+			}
+			defer e.Leave()
+
+			for _, f := range fields {
+				fv, ok := v[f.name]
+				if !ok {
+					return fmt.Errorf("%s: missing field %s", name, f.name)
+				}
+				if err := f.codec.Encode(e, fv); err != nil {
+					return fmt.Errorf("%s.%s: %w", name, f.name, err)
+				}
+			}
+			if len(v) != len(fields) {
+				for k := range v {
+					if _, ok := names[k]; !ok {
+						return fmt.Errorf("%s: unknown field %s", name, k)
+					}
+				}
+			}
+
+			return nil
+		},
+		decode: func(d *Decoder) (map[string]any, error) {
+			if err := d.Enter(); err != nil {
+				return nil, err
+			}
+			defer d.Leave()
+
+			r := make(map[string]any, len(fields))
+			for _, f := range fields {
+				fv, err := f.codec.Decode(d)
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s: %w", name, f.name, err)
+				}
+				r[f.name] = fv
+			}
+
+			return r, nil
+		},
+	}
+}
+
+// EnumVariant is a variant of [EnumOf], created by [Variant].
+type EnumVariant struct {
+	name  string
+	codec Codec[any]
+}
+
+// Variant creates a variant of name for [EnumOf], whose value is encoded with c. Use [Unit] for variants without values.
+func Variant[T any](name string, c Codec[T]) EnumVariant {
+	return EnumVariant{name: name, codec: Any(c)}
+}
+
+// EnumValue is the value of [EnumOf].
+type EnumValue struct {
+	// Variant is the name of the variant.
+	Variant string
+	// Value is the value of the variant, which is struct{}{} for [Unit] variants.
+	Value any
+}
+
+// EnumOf is an enum of the variants, whose indices are their positions in variants.
+// name is used in errors. EnumOf panics if the names of the variants are not unique.
+func EnumOf(name string, variants ...EnumVariant) Codec[EnumValue] {
+	indices := make(map[string]int, len(variants))
+	for i, v := range variants {
+		if _, ok := indices[v.name]; ok {
+			panic(fmt.Sprintf("bcs: duplicate variant %s in enum %s", v.name, name))
+		}
+		indices[v.name] = i
+	}
+
+	return codecFunc[EnumValue]{
+		encode: func(e *Encoder, v EnumValue) error {
+			i, ok := indices[v.Variant]
+			if !ok {
+				return fmt.Errorf("%s: unknown variant %s", name, v.Variant)
+			}
+
+			if err := e.Enter(); err != nil {
+				return err
+			}
+			defer e.Leave()
+
+			if err := e.WriteVariant(uint32(i)); err != nil {
+				return err
+			}
+			if err := variants[i].codec.Encode(e, v.Value); err != nil {
+				return fmt.Errorf("%s::%s: %w", name, v.Variant, err)
+			}
+
+			return nil
+		},
+		decode: func(d *Decoder) (EnumValue, error) {
+			if err := d.Enter(); err != nil {
+				return EnumValue{}, err
+			}
+			defer d.Leave()
+
+			i, err := d.ReadVariant()
+			if err != nil {
+				return EnumValue{}, err
+			}
+			if int(i) >= len(variants) {
+				return EnumValue{}, fmt.Errorf("%s: variant %d is out of range", name, i)
+			}
+			variant := variants[i]
+			v, err := variant.codec.Decode(d)
+			if err != nil {
+				return EnumValue{}, fmt.Errorf("%s::%s: %w", name, variant.name, err)
+			}
+
+			return EnumValue{Variant: variant.name, Value: v}, nil
+		},
+	}
+}
+
+// MapOf is a map, which is encoded as a vector of key value pairs sorted by the bytes of the encoded keys,
+// as is defined by bcs. Decoding errors if the keys are not sorted or are duplicated, so the encoding is canonical.
+func MapOf[K comparable, V any](k Codec[K], v Codec[V]) Codec[map[K]V] {
+	type entry struct {
+		key   []byte
+		value V
+	}
+
+	return codecFunc[map[K]V]{
+		encode: func(e *Encoder, m map[K]V) error {
+			entries := make([]entry, 0, len(m))
+			for mk, mv := range m {
+				key, err := MarshalWith(k, mk)
+				if err != nil {
+					return fmt.Errorf("key %v: %w", mk, err)
+				}
+				entries = append(entries, entry{key: key, value: mv})
+			}
+			sort.Slice(entries, func(i, j int) bool {
+				return bytes.Compare(entries[i].key, entries[j].key) < 0
+			})
+
+			if err := e.WriteLength(len(entries)); err != nil {
+				return err
+			}
+			for _, en := range entries {
+				if err := e.WriteFixedBytes(en.key); err != nil {
+					return err
+				}
+				if err := v.Encode(e, en.value); err != nil {
+					return fmt.Errorf("value of key %x: %w", en.key, err)
+				}
+			}
+
+			return nil
+		},
+		decode: func(d *Decoder) (map[K]V, error) {
+			size, err := d.ReadLength()
+			if err != nil {
+				return nil, err
+			}
+
+			var zeroK K
+			var zeroV V
+			r := make(map[K]V, min(size, max(1, maxChunkSize/max(1, int(unsafe.Sizeof(zeroK)+unsafe.Sizeof(zeroV))))))
+			var prev []byte
+			for i := 0; i < size; i++ {
+				start := d.startCapture()
+				mk, err := k.Decode(d)
+				key := d.stopCapture(start)
+				if err != nil {
+					return nil, fmt.Errorf("key %d: %w", i, err)
+				}
+				if i > 0 && bytes.Compare(prev, key) >= 0 {
+					return nil, fmt.Errorf("key %d: keys are not sorted or are duplicated", i)
+				}
+				prev = key
+
+				mv, err := v.Decode(d)
+				if err != nil {
+					return nil, fmt.Errorf("value of key %v: %w", mk, err)
+				}
+				r[mk] = mv
+			}
+
+			return r, nil
+		},
+	}
+}
@@ -0,0 +1,214 @@
+package bcs_test
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+type Coin struct {
+	ID    [32]byte
+	Value uint64
+	Tags  []string
+	Owner *uint16 `bcs:"optional"`
+}
+
+var coinCodec = bcs.StructOf("Coin",
+	bcs.Field("id", bcs.FixedBytes(32)),
+	bcs.Field("value", bcs.U64),
+	bcs.Field("tags", bcs.Vec(bcs.String)),
+	bcs.Field("owner", bcs.OptionOf(bcs.U16)),
+)
+
+func TestStructOf(t *testing.T) {
+	owner := uint16(7)
+	coin := Coin{ID: [32]byte{1, 2}, Value: 100, Tags: []string{"a", "bc"}, Owner: &owner}
+	expected := bcs.MustMarshal(coin)
+
+	v := map[string]any{
+		"id":    coin.ID[:],
+		"value": coin.Value,
+		"tags":  coin.Tags,
+		"owner": coin.Owner,
+	}
+	b, err := bcs.MarshalWith(coinCodec, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(b, expected) {
+		t.Fatalf("want: %v\ngot:  %v", expected, b)
+	}
+
+	decoded, err := bcs.UnmarshalWith(coinCodec, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(bcs.MustMarshal(decoded["tags"]), bcs.MustMarshal(coin.Tags)) || decoded["value"] != uint64(100) {
+		t.Fatalf("unexpected decoded value: %v", decoded)
+	}
+
+	delete(v, "tags")
+	if _, err := bcs.MarshalWith(coinCodec, v); err == nil || !strings.Contains(err.Error(), "missing field tags") {
+		t.Fatalf("want missing field error, got: %v", err)
+	}
+	v["tags"] = []int{1}
+	if _, err := bcs.MarshalWith(coinCodec, v); err == nil || !strings.Contains(err.Error(), "Coin.tags: expected []string, got []int") {
+		t.Fatalf("want type error, got: %v", err)
+	}
+	v["tags"] = coin.Tags
+	v["extra"] = 1
+	if _, err := bcs.MarshalWith(coinCodec, v); err == nil || !strings.Contains(err.Error(), "unknown field extra") {
+		t.Fatalf("want unknown field error, got: %v", err)
+	}
+}
+
+func TestStructOf_buffered(t *testing.T) {
+	owner := uint16(7)
+	coin := Coin{ID: [32]byte{1, 2}, Value: 100, Tags: []string{"a", "bc"}, Owner: &owner}
+	v := map[string]any{
+		"id":    coin.ID[:],
+		"value": coin.Value,
+		"tags":  coin.Tags,
+		"owner": coin.Owner,
+	}
+
+	w := &countingWriter{}
+	e := bcs.NewEncoder(w)
+	if err := coinCodec.Encode(e, v); err != nil {
+		t.Fatal(err)
+	}
+	if w.writes != 1 || !slices.Equal(w.data, bcs.MustMarshal(coin)) {
+		t.Fatalf("want the coin in 1 write, got %d writes of %v", w.writes, w.data)
+	}
+
+	// the partial output of a failed encoding is discarded.
+	delete(v, "tags")
+	if err := coinCodec.Encode(e, v); err == nil {
+		t.Fatal("want missing field error")
+	}
+	if w.writes != 1 {
+		t.Fatalf("want no write for failed encoding, got %d writes", w.writes)
+	}
+}
+
+func TestEnumOf(t *testing.T) {
+	c := bcs.EnumOf("EnumExample",
+		bcs.Variant("V0", bcs.U8),
+		bcs.Variant("V1", bcs.Unit),
+		bcs.Variant("V2", bcs.U32),
+	)
+
+	v2 := uint32(9)
+	expected := bcs.MustMarshal(EnumExample{V2: &v2})
+
+	b, err := bcs.MarshalWith(c, bcs.EnumValue{Variant: "V2", Value: v2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(b, expected) {
+		t.Fatalf("want: %v\ngot:  %v", expected, b)
+	}
+
+	decoded, err := bcs.UnmarshalWith(c, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != (bcs.EnumValue{Variant: "V2", Value: v2}) {
+		t.Fatalf("unexpected decoded value: %v", decoded)
+	}
+
+	if _, err := bcs.UnmarshalWith(c, []byte{3}); err == nil {
+		t.Fatal("want error for out of range variant")
+	}
+	if _, err := bcs.MarshalWith(c, bcs.EnumValue{Variant: "V3"}); err == nil {
+		t.Fatal("want error for unknown variant")
+	}
+}
+
+func TestMapOf(t *testing.T) {
+	c := bcs.MapOf(bcs.String, bcs.U8)
+
+	b, err := bcs.MarshalWith(c, map[string]uint8{"b": 2, "a": 1, "aa": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// keys are sorted by their encoding, where the length comes first.
+	expected := []byte{3, 1, 'a', 1, 1, 'b', 2, 2, 'a', 'a', 3}
+	if !slices.Equal(b, expected) {
+		t.Fatalf("want: %v\ngot:  %v", expected, b)
+	}
+
+	m, err := bcs.UnmarshalWith(c, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 3 || m["aa"] != 3 {
+		t.Fatalf("unexpected decoded value: %v", m)
+	}
+
+	for _, data := range [][]byte{
+		{2, 1, 'b', 2, 1, 'a', 1},
+		{2, 1, 'a', 2, 1, 'a', 1},
+	} {
+		if _, err := bcs.UnmarshalWith(c, data); err == nil || !strings.Contains(err.Error(), "not sorted") {
+			t.Fatalf("want error for non canonical map, got: %v", err)
+		}
+		if _, err := c.Decode(bcs.NewDecoder(bytes.NewReader(data))); err == nil {
+			t.Fatalf("want error for non canonical map from reader")
+		}
+	}
+}
+
+func TestVec(t *testing.T) {
+	c := bcs.Vec(bcs.Vec(bcs.U128))
+	v := [][]bcs.Uint128{{*bcs.NewUint128FromUint64(1, 2)}, {}}
+
+	b, err := bcs.MarshalWith(c, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(b, bcs.MustMarshal(v)) {
+		t.Fatalf("want: %v\ngot:  %v", bcs.MustMarshal(v), b)
+	}
+
+	// declares 2^32-1 elements.
+	if _, err := bcs.UnmarshalWith(bcs.Vec(bcs.U64), []byte{0xff, 0xff, 0xff, 0xff, 0x0f, 1}); err == nil {
+		t.Fatal("want error for truncated input")
+	}
+
+	if _, err := bcs.MarshalWith(bcs.Array(2, bcs.U8), []uint8{1}); err == nil {
+		t.Fatal("want error for wrong number of elements")
+	}
+}
+
+func ExampleStructOf() {
+	// the layout of the value is known at runtime.
+	layout := []string{"u64", "bool", "string"}
+
+	fields := make([]bcs.StructField, 0, len(layout))
+	for i, typ := range layout {
+		var c bcs.Codec[any]
+		switch typ {
+		case "u64":
+			c = bcs.Any(bcs.U64)
+		case "bool":
+			c = bcs.Any(bcs.Bool)
+		case "string":
+			c = bcs.Any(bcs.String)
+		}
+		fields = append(fields, bcs.Field(fmt.Sprintf("field_%d", i), c))
+	}
+	codec := bcs.StructOf("Dynamic", fields...)
+
+	v, err := bcs.UnmarshalWith(codec, []byte{42, 0, 0, 0, 0, 0, 0, 0, 1, 2, 'h', 'i'})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(v["field_0"], v["field_1"], v["field_2"])
+
+	// Output: 42 true hi
+}
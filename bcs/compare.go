@@ -0,0 +1,146 @@
+package bcs
+
+import (
+	"bytes"
+	"errors"
+	"hash"
+	"iter"
+	"slices"
+)
+
+// Equal checks if a and b have the same encoding, which is the equality used to deduplicate values.
+// It is the same as [Compare] returning 0.
+func Equal(a, b any) (bool, error) {
+	c, err := Compare(a, b)
+	if err != nil {
+		return false, err
+	}
+
+	return c == 0, nil
+}
+
+// Compare compares the encodings of a and b lexicographically, which is the order of the keys of BTreeMap
+// in rust and of the comparator in move. The result is 0 if a and b have the same encoding, -1 if a is before b,
+// and +1 if a is after b.
+//
+// The encodings are compared as they are produced, without encoding the values in full,
+// and the comparison stops at the first different byte. Hence an error encoding the rest of a value may not be reported
+// if the values are already different before it.
+func Compare(a, b any) (int, error) {
+	sa := newEncodingStream(a)
+	defer sa.stop()
+	sb := newEncodingStream(b)
+	defer sb.stop()
+
+	for {
+		ca, err := sa.chunk()
+		if err != nil {
+			return 0, err
+		}
+		cb, err := sb.chunk()
+		if err != nil {
+			return 0, err
+		}
+
+		switch {
+		case len(ca) == 0 && len(cb) == 0:
+			return 0, nil
+		case len(ca) == 0:
+			return -1, nil
+		case len(cb) == 0:
+			return 1, nil
+		}
+
+		n := min(len(ca), len(cb))
+		if c := bytes.Compare(ca[:n], cb[:n]); c != 0 {
+			return c, nil
+		}
+		sa.rest, sb.rest = ca[n:], cb[n:]
+	}
+}
+
+// SortSlice sorts s by the encodings of its elements, see [Compare]. The sort is stable, so elements
+// with the same encoding keep their order.
+//
+// Each element is encoded once, instead of on every comparison.
+func SortSlice[T any](s []T) error {
+	type keyed struct {
+		key []byte
+		v   T
+	}
+
+	keys := make([]keyed, len(s))
+	for i := range s {
+		// the elements are encoded as values, the same as [Compare].
+		key, err := Marshal(s[i])
+		if err != nil {
+			return WithIndex(err, i)
+		}
+		keys[i] = keyed{key: key, v: s[i]}
+	}
+
+	slices.SortStableFunc(keys, func(a, b keyed) int {
+		return bytes.Compare(a.key, b.key)
+	})
+	for i := range keys {
+		s[i] = keys[i].v
+	}
+
+	return nil
+}
+
+// Hash writes the encoding of v into h, without holding the encoding in memory.
+// The sum of h is not reset before or read after.
+func Hash(v any, h hash.Hash) error {
+	return NewEncoder(h).Encode(v)
+}
+
+// errStreamStopped is returned by the writer of [encodingStream] when the stream is stopped before the end.
+var errStreamStopped = errors.New("encoding stream is stopped")
+
+// encodingStream produces the encoding of a value in chunks, one flush of the [Encoder] at a time.
+// The encoder runs in a coroutine with [iter.Pull2], so the value doesn't need to be encoded in full.
+type encodingStream struct {
+	next func() ([]byte, error, bool)
+	stop func()
+	// rest is the part of the current chunk not consumed yet.
+	rest []byte
+	done bool
+}
+
+func newEncodingStream(v any) *encodingStream {
+	seq := func(yield func([]byte, error) bool) {
+		err := NewEncoder(streamWriter(yield)).Encode(v)
+		if err != nil && !errors.Is(err, errStreamStopped) {
+			yield(nil, err)
+		}
+	}
+	next, stop := iter.Pull2(seq)
+
+	return &encodingStream{next: next, stop: stop}
+}
+
+// chunk returns the bytes not consumed yet, reading the next chunk if the current one is consumed.
+// It returns empty at the end of the encoding. The returned bytes are only valid until the next call.
+func (s *encodingStream) chunk() ([]byte, error) {
+	for len(s.rest) == 0 && !s.done {
+		b, err, ok := s.next()
+		if err != nil {
+			return nil, err
+		}
+		s.rest, s.done = b, !ok
+	}
+
+	return s.rest, nil
+}
+
+// streamWriter passes the bytes written to it to yield.
+type streamWriter func([]byte, error) bool
+
+func (w streamWriter) Write(b []byte) (int, error) {
+	if !w(b, nil) {
+		return 0, errStreamStopped
+	}
+
+	return len(b), nil
+}
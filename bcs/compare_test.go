@@ -0,0 +1,104 @@
+package bcs_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math/rand"
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+type Key struct {
+	Module string
+	Data   []byte
+}
+
+func TestCompare(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	random := func() Key {
+		// long data crosses the chunks written by the encoder.
+		data := make([]byte, r.Intn(3)*5000)
+		r.Read(data[:min(len(data), 2)])
+		return Key{Module: []string{"a", "b", "ab"}[r.Intn(3)], Data: data}
+	}
+
+	for i := 0; i < 200; i++ {
+		a, b := random(), random()
+		if i%10 == 0 {
+			b = a
+		}
+		want := bytes.Compare(bcs.MustMarshal(a), bcs.MustMarshal(b))
+		got, err := bcs.Compare(a, &b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("compare %v and %v, want %d, got %d", a.Module, b.Module, want, got)
+		}
+		if equal, err := bcs.Equal(a, b); err != nil || equal != (want == 0) {
+			t.Fatalf("equal want %t, got %t, %v", want == 0, equal, err)
+		}
+	}
+
+	if _, err := bcs.Compare(Key{}, map[int]int{}); err == nil {
+		t.Fatal("want error for unsupported type")
+	}
+	if equal, err := bcs.Equal(map[int]int{}, map[int]int{}); err == nil || equal {
+		t.Fatalf("want not equal and error for unsupported type, got %t, %v", equal, err)
+	}
+}
+
+func TestSortSlice(t *testing.T) {
+	keys := []Key{
+		{Module: "b"},
+		{Module: "a", Data: []byte{2}},
+		{Module: "ab"},
+		{Module: "a", Data: []byte{1}},
+	}
+	if err := bcs.SortSlice(keys); err != nil {
+		t.Fatal(err)
+	}
+
+	// shorter strings are first since the length is encoded first.
+	want := []string{"a", "a", "b", "ab"}
+	for i, k := range keys {
+		if k.Module != want[i] {
+			t.Fatalf("want %v at %d, got %v", want[i], i, k.Module)
+		}
+	}
+	if keys[0].Data[0] != 1 {
+		t.Fatalf("want data 1 first, got %v", keys[0].Data)
+	}
+
+	if err := bcs.SortSlice([]int{2, 1}); err == nil {
+		t.Fatal("want error for int")
+	}
+}
+
+func TestHash(t *testing.T) {
+	v := Key{Module: "coin", Data: make([]byte, 10000)}
+	h := sha256.New()
+	if err := bcs.Hash(v, h); err != nil {
+		t.Fatal(err)
+	}
+
+	want := sha256.Sum256(bcs.MustMarshal(v))
+	if !bytes.Equal(h.Sum(nil), want[:]) {
+		t.Fatal("hash doesn't match the hash of the encoding")
+	}
+}
+
+func TestSortSlice_pointerMarshaler(t *testing.T) {
+	// Compact encodes only the lowest byte, so 256 is before 1.
+	s := []Compact{{V: 1}, {V: 256}}
+	if err := bcs.SortSlice(s); err != nil {
+		t.Fatal(err)
+	}
+	if s[0].V != 256 || s[1].V != 1 {
+		t.Fatalf("want [256 1], got %v", s)
+	}
+	if c, err := bcs.Compare(s[0], s[1]); err != nil || c != -1 {
+		t.Fatalf("want -1 consistent with the sort, got %d %v", c, err)
+	}
+}
@@ -0,0 +1,126 @@
+package bcs
+
+import (
+	"fmt"
+	"reflect"
+	"unicode/utf8"
+
+	"github.com/fardream/go-bcs/internal/bcstag"
+)
+
+// constraints are the checks on the value of a field declared in its tag, such as `bcs:"maxlen=256"`.
+// They are checked after the field is decoded and before it is encoded, except maxlen, which is checked
+// right after the length is decoded, before the vector is allocated.
+//
+// Pointers are checked through the values they point to, and nil pointers are not checked.
+type constraints struct {
+	// maxLen is the max length of a vector or string, -1 if not set.
+	maxLen int
+	// min and max are the bounds of integers as in the tag, which are parsed into
+	// minInt and maxInt or minUint and maxUint by the signedness of the field, see [constraints.compile].
+	min, max         string
+	minInt, maxInt   int64
+	minUint, maxUint uint64
+	signed           bool
+	utf8             bool
+	ascii            bool
+}
+
+// compile checks the constraints can be applied to the field of plan p, and parses the bounds of integers.
+func (c *constraints) compile(p *typePlan) error {
+	for p.kind == reflect.Pointer {
+		p = p.elem
+	}
+
+	isString := p.kind == reflect.String
+	isBytes := p.kind == reflect.Slice && p.elem.kind == reflect.Uint8
+	if c.maxLen >= 0 && (!isString && p.kind != reflect.Slice || p.hasCustomUnmarshaler() || p.isEnum) {
+		return fmt.Errorf("tag maxlen can only be used on vectors and strings, got %s", p.typ.String())
+	}
+	if (c.utf8 || c.ascii) && !isString && !isBytes {
+		return fmt.Errorf("tag utf8 and ascii can only be used on strings and []byte, got %s", p.typ.String())
+	}
+	if c.min == "" && c.max == "" {
+		return nil
+	}
+
+	bits := int(p.typ.Size()) * 8
+	var err error
+	switch p.kind {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		c.signed = true
+		c.minInt, c.maxInt, err = bcstag.IntBounds(c.min, c.max, bits)
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		c.minUint, c.maxUint, err = bcstag.UintBounds(c.min, c.max, bits)
+	default:
+		return fmt.Errorf("tag min and max can only be used on integers, got %s", p.typ.String())
+	}
+	if err != nil {
+		return fmt.Errorf("%w for %s", err, p.typ.String())
+	}
+
+	return nil
+}
+
+// check checks the value of the field, and returns a [*ValidationError] if the value violates the constraints.
+func (c *constraints) check(v reflect.Value) error {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	var err error
+	switch {
+	case c.maxLen >= 0 && v.Len() > c.maxLen:
+		err = fmt.Errorf("length %d exceeds maxlen %d", v.Len(), c.maxLen)
+	case c.min == "" && c.max == "":
+	case c.signed && (v.Int() < c.minInt || v.Int() > c.maxInt):
+		err = fmt.Errorf("%d is out of range [%d, %d]", v.Int(), c.minInt, c.maxInt)
+	case !c.signed && (v.Uint() < c.minUint || v.Uint() > c.maxUint):
+		err = fmt.Errorf("%d is out of range [%d, %d]", v.Uint(), c.minUint, c.maxUint)
+	}
+	if err == nil && (c.utf8 || c.ascii) {
+		err = checkText(v, c.ascii)
+	}
+	if err != nil {
+		return &ValidationError{Err: err}
+	}
+
+	return nil
+}
+
+// checkText checks a string or []byte is valid utf8, or ascii if ascii is true.
+func checkText(v reflect.Value, ascii bool) error {
+	var valid bool
+	switch {
+	case ascii && v.Kind() == reflect.String:
+		valid = isASCII(v.String())
+	case ascii:
+		valid = isASCII(v.Bytes())
+	case v.Kind() == reflect.String:
+		valid = utf8.ValidString(v.String())
+	default:
+		valid = utf8.Valid(v.Bytes())
+	}
+
+	switch {
+	case valid:
+		return nil
+	case ascii:
+		return fmt.Errorf("not ascii")
+	default:
+		return fmt.Errorf("not valid utf8")
+	}
+}
+
+func isASCII[T string | []byte](s T) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+
+	return true
+}
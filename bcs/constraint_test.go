@@ -0,0 +1,147 @@
+package bcs_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+type Constrained struct {
+	Name    string   `bcs:"maxlen=8,ascii"`
+	Memo    *string  `bcs:"optional,utf8,maxlen=16"`
+	Tags    []uint16 `bcs:"maxlen=2"`
+	Percent uint8    `bcs:"min=1,max=100"`
+	Offset  int32    `bcs:"min=-10"`
+	Data    []byte   `bcs:"maxlen=4"`
+}
+
+func TestConstraints(t *testing.T) {
+	memo := "héllo"
+	valid := Constrained{Name: "coin", Memo: &memo, Tags: []uint16{1, 2}, Percent: 100, Offset: -10, Data: []byte{1}}
+	b, err := bcs.Marshal(&valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := bcs.UnmarshalAs[Constrained](b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Name != "coin" || *decoded.Memo != memo || decoded.Percent != 100 {
+		t.Fatalf("unexpected decoded value: %v", decoded)
+	}
+
+	invalid := "\xff"
+	for _, c := range []struct {
+		path   string
+		modify func(v *Constrained)
+	}{
+		{"Name", func(v *Constrained) { v.Name = "too long name" }},
+		{"Name", func(v *Constrained) { v.Name = "é" }},
+		{"Memo", func(v *Constrained) { v.Memo = &invalid }},
+		{"Tags", func(v *Constrained) { v.Tags = []uint16{1, 2, 3} }},
+		{"Percent", func(v *Constrained) { v.Percent = 0 }},
+		{"Percent", func(v *Constrained) { v.Percent = 101 }},
+		{"Offset", func(v *Constrained) { v.Offset = -11 }},
+		{"Data", func(v *Constrained) { v.Data = make([]byte, 5) }},
+	} {
+		v := valid
+		c.modify(&v)
+
+		var ve *bcs.ValidationError
+		if _, err := bcs.Marshal(&v); !errors.As(err, &ve) || ve.Path != c.path {
+			t.Fatalf("want validation error at %s when encoding, got %v", c.path, err)
+		}
+
+		type unconstrained struct {
+			Name    string
+			Memo    *string `bcs:"optional"`
+			Tags    []uint16
+			Percent uint8
+			Offset  int32
+			Data    []byte
+		}
+		b := bcs.MustMarshal((*unconstrained)(&v))
+		if _, err := bcs.UnmarshalAs[Constrained](b); !errors.As(err, &ve) || ve.Path != c.path {
+			t.Fatalf("want validation error at %s when decoding, got %v", c.path, err)
+		}
+	}
+}
+
+func TestConstraints_maxLenBeforeAllocation(t *testing.T) {
+	// the declared length is far larger than the input, and is rejected by maxlen instead of io.ErrUnexpectedEOF.
+	_, err := bcs.UnmarshalAs[Constrained]([]byte{0xff, 0xff, 0xff, 0xff, 0x07})
+	var ve *bcs.ValidationError
+	if !errors.As(err, &ve) || ve.Error() != "validation failed at Name: length 2147483647 exceeds maxlen 8" {
+		t.Fatalf("want maxlen error, got %v", err)
+	}
+}
+
+// Bounded has the memory layout of its encoding, but must not be copied as is because of the constraint.
+type Bounded struct {
+	A uint16 `bcs:"max=10"`
+	B uint8
+	C uint8
+}
+
+func TestConstraints_sliceAndArray(t *testing.T) {
+	type wrapper struct {
+		Slice []Bounded
+		Array [1]Bounded
+	}
+
+	for _, c := range []struct {
+		path string
+		v    wrapper
+	}{
+		{"Slice[0].A", wrapper{Slice: []Bounded{{A: 100}}}},
+		{"Array[0].A", wrapper{Slice: []Bounded{}, Array: [1]Bounded{{A: 100}}}},
+	} {
+		var ve *bcs.ValidationError
+		if _, err := bcs.Marshal(&c.v); !errors.As(err, &ve) || ve.Path != c.path {
+			t.Fatalf("want validation error at %s when encoding, got %v", c.path, err)
+		}
+
+		// the same layout without the constraint, which struct conversion ignores.
+		type unconstrained struct {
+			A    uint16
+			B, C uint8
+		}
+		var u struct {
+			Slice []unconstrained
+			Array [1]unconstrained
+		}
+		for _, e := range c.v.Slice {
+			u.Slice = append(u.Slice, unconstrained(e))
+		}
+		u.Array[0] = unconstrained(c.v.Array[0])
+		b := bcs.MustMarshal(&u)
+		if _, err := bcs.UnmarshalAs[wrapper](b); !errors.As(err, &ve) || ve.Path != c.path {
+			t.Fatalf("want validation error at %s when decoding, got %v", c.path, err)
+		}
+	}
+}
+
+func TestConstraints_invalidTag(t *testing.T) {
+	for _, v := range []any{
+		&struct {
+			A uint8 `bcs:"maxlen=1"`
+		}{},
+		&struct {
+			A []uint8 `bcs:"min=1"`
+		}{},
+		&struct {
+			A uint8 `bcs:"max=256"`
+		}{},
+		&struct {
+			A []uint16 `bcs:"utf8"`
+		}{},
+		&struct {
+			A string `bcs:"maxlen=-1"`
+		}{},
+	} {
+		if _, err := bcs.Marshal(v); err == nil {
+			t.Fatalf("want error for %T", v)
+		}
+	}
+}
@@ -1,27 +1,33 @@
 package bcs
 
 import (
-	"bytes"
-	"container/list"
-	"encoding/binary"
 	"fmt"
 	"io"
 	"reflect"
+	"unsafe"
 )
 
 // maxChunkSize is the maximum size to allocate at once, limiting DoS attacks
 const maxChunkSize = 1024 * 1024 // 1MB chunks
 
+// DefaultMaxDepth is the default max depth of nested structs, enums, and customized (un)marshalers,
+// which is the same as the MAX_CONTAINER_DEPTH of the rust implementation.
+const DefaultMaxDepth = 500
+
 // Unmarshal unmarshals the bcs serialized data into v.
 //
 // Refer to notes in [Marshal] for details how data serialized/deserialized.
 //
 // During the unmarshalling process
-//  1. if [Unmarshaler], use "UnmarshalBCS" method.
-//  2. if not [Unmarshaler] but [Enum], use the specialization for [Enum].
-//  3. otherwise standard process.
+//  1. if [UnmarshalerFrom], use "UnmarshalBCSFrom" method.
+//  2. if [Unmarshaler], use "UnmarshalBCS" method.
+//  3. if not [Unmarshaler] but [Enum], use the specialization for [Enum].
+//  4. otherwise standard process.
+//
+// Bools and the tags of optional fields must be 0 or 1, and other values error, since they are not canonical.
+// An empty vector<u8> is decoded as an empty but non-nil []byte, which replaces the value of v.
 func Unmarshal(data []byte, v any) (int, error) {
-	return NewDecoder(bytes.NewReader(data)).Decode(v)
+	return NewBytesDecoder(data).Decode(v)
 }
 
 // UnmarshalAll is like [Unmarshal], but will additionally error if the input
@@ -30,7 +36,7 @@ func Unmarshal(data []byte, v any) (int, error) {
 // This is useful for ensuring that a particular set of bytes *completely* represents
 // the data it is decoded to (i.e. no bytes are left over after decoding).
 func UnmarshalAll(data []byte, v any) error {
-	if n, err := NewDecoder(bytes.NewReader(data)).Decode(v); err != nil {
+	if n, err := NewBytesDecoder(data).Decode(v); err != nil {
 		return err
 	} else if n != len(data) {
 		return fmt.Errorf("did not unmarshal all bytes, got %d expected %d", n, len(data))
@@ -40,20 +46,119 @@ func UnmarshalAll(data []byte, v any) error {
 }
 
 // Decoder takes an [io.Reader] and decodes value from it.
+//
+// Besides decoding values with [Decoder.Decode], the decoder exposes methods to read the
+// primitives of bcs, such as [Decoder.ReadU64] or [Decoder.ReadULEB128], which can be
+// used to implement customized unmarshalers.
 type Decoder struct {
-	reader     io.Reader
-	byteBuffer [1]byte
+	reader io.Reader
+	// data is the input of the decoder created by [NewBytesDecoder], nil otherwise.
+	data     []byte
+	zeroCopy bool
+	offset   int
+	scratch  [32]byte
+	depth    int
+	maxDepth int
+	version  int
+	seed     any
+
+	// bytes recorded for [Raw] while capturing > 0, only used when the input is an [io.Reader].
+	captured  []byte
+	capturing int
 }
 
 // NewDecoder creates a new [Decoder] from an [io.Reader]
 func NewDecoder(r io.Reader) *Decoder {
 	return &Decoder{
-		reader: r,
+		reader:   r,
+		maxDepth: DefaultMaxDepth,
+	}
+}
+
+// NewBytesDecoder creates a new [Decoder] from in memory bytes.
+//
+// Compared with [NewDecoder], the decoder reads directly from data instead of going
+// through an [io.Reader], and can optionally decode []byte and string without copying, see [Decoder.SetZeroCopy].
+func NewBytesDecoder(data []byte) *Decoder {
+	if data == nil {
+		data = []byte{}
+	}
+
+	return &Decoder{
+		data:     data,
+		maxDepth: DefaultMaxDepth,
 	}
 }
 
-// DecodeWithSize decodes a value from the decoder, and returns the number of bytes it consumed from the decoder.
+// SetZeroCopy sets if []byte and string decoded by a decoder created by [NewBytesDecoder]
+// should alias the input instead of being copied. The input must not be modified
+// while the decoded values are in use. This is ignored for decoders reading from an [io.Reader].
 //
+// Zero copy can also be turned on for individual fields with tag `nocopy`.
+func (d *Decoder) SetZeroCopy(zeroCopy bool) {
+	d.zeroCopy = zeroCopy
+}
+
+// ZeroCopy returns if zero copy is on, see [Decoder.SetZeroCopy].
+func (d *Decoder) ZeroCopy() bool {
+	return d.zeroCopy
+}
+
+// SetMaxDepth sets the max depth of nested structs, enums, and customized unmarshalers
+// the decoder will go into. Non-positive value removes the limit.
+func (d *Decoder) SetMaxDepth(maxDepth int) {
+	d.maxDepth = maxDepth
+}
+
+// Seed returns the seed of the decoder, see [Decoder.DecodeSeed].
+func (d *Decoder) Seed() any {
+	return d.seed
+}
+
+// SetSeed sets the seed of the decoder, see [Decoder.DecodeSeed].
+func (d *Decoder) SetSeed(seed any) {
+	d.seed = seed
+}
+
+// DecodeSeed is like [Decoder.Decode], but replaces the seed of the decoder with seed
+// while v is decoded, and restores the previous seed afterwards.
+//
+// The seed is runtime data the decoding of a value depends on, similar to DeserializeSeed of serde in rust.
+// For example, the type layout of a move value, or the length of a vector that is known from a header
+// instead of being prefixed to the vector. [UnmarshalerFrom] implementations can read the seed
+// with [Decoder.Seed], and pass a different seed to the values nested inside them with DecodeSeed.
+func (d *Decoder) DecodeSeed(seed any, v any) (int, error) {
+	prev := d.seed
+	d.seed = seed
+	defer func() { d.seed = prev }()
+
+	return d.Decode(v)
+}
+
+// Enter increases the depth of nested values, and errors if the max depth is exceeded.
+// Call [Decoder.Leave] after the nested value is decoded.
+//
+// Decoding structs, enums, and [UnmarshalerFrom] through [Decoder.Decode] already counts the depth.
+// Implementations of [UnmarshalerFrom] calling each other directly, such as the code generated
+// by bcsgen, can use this to keep the protection against deeply nested input.
+func (d *Decoder) Enter() error {
+	d.depth++
+	if d.maxDepth > 0 && d.depth > d.maxDepth {
+		d.depth--
+		return fmt.Errorf("exceeded max depth %d", d.maxDepth)
+	}
+
+	return nil
+}
+
+// Leave decreases the depth increased by [Decoder.Enter].
+func (d *Decoder) Leave() {
+	d.depth--
+}
+
+// Decode decodes a value from the decoder, and returns the number of bytes it consumed from the decoder.
+//
+//   - If the value is [UnmarshalerFrom], the corresponding UnmarshalBCSFrom will be called.
 //   - If the value is [Unmarshaler], the corresponding UnmarshalBCS will be called.
 //   - If the value is [Enum], it will be special handled for [Enum]
 func (d *Decoder) Decode(v any) (int, error) {
@@ -62,373 +167,401 @@ func (d *Decoder) Decode(v any) (int, error) {
 		return 0, fmt.Errorf("not a pointer or nil pointer")
 	}
 
-	return d.decode(reflectValue)
+	start := d.offset
+	err := d.decode(reflectValue)
+
+	return d.offset - start, err
+}
+
+// decode decodes into v following the plan of its type.
+func (d *Decoder) decode(v reflect.Value) error {
+	return d.decodeValue(v, planFor(v.Type()))
 }
 
-// decode is the main lifter, it first checks if a value can be [reflect.Value.CanInterface],
-// then checks if the value implements [Unmarshaler] or [Enum], and then switch on the kind of the value:
+// decodeValue is the main lifter, it first checks if the value implements [UnmarshalerFrom], [Unmarshaler] or [Enum],
+// and then switch on the kind of the value:
 // - pointer, create a new one and decode into its element.
 // - interface, decode into element.
 // - function, channel, unsafe pointers, ignore
 // - otherwise call [decodeVanilla].
-func (d *Decoder) decode(v reflect.Value) (int, error) {
-	// if v cannot interface, ignore
-	if !v.CanInterface() {
-		return 0, nil
-	}
-
-	vAddr := v
-	if v.Kind() != reflect.Pointer && v.CanAddr() {
-		vAddr = v.Addr()
+func (d *Decoder) decodeValue(v reflect.Value, p *typePlan) error {
+	if err := d.decodeUnvalidated(v, p); err != nil || !p.validator {
+		return err
 	}
 
-	// Unmarshaler
-	if i, isUnmarshaler := vAddr.Interface().(Unmarshaler); isUnmarshaler {
-		return i.UnmarshalBCS(d.reader)
-	}
+	return validate(v)
+}
 
-	// Enum
-	if _, isEnum := v.Interface().(Enum); isEnum {
-		switch v.Kind() {
-		case reflect.Pointer:
-			if v.IsNil() {
-				v.Set(reflect.New(v.Type().Elem()))
-			}
-			return d.decodeEnum(v.Elem())
-		case reflect.Interface:
-			if v.IsNil() {
-				return 0, fmt.Errorf("trying to decode into nil pointer/interface")
-			}
-			return d.decodeEnum(v.Elem())
-		default:
-			return d.decodeEnum(v)
-		}
+// decodeUnvalidated decodes a value following the plan of its type without calling [BCSValidator] of the value,
+// see [Decoder.decodeValue].
+func (d *Decoder) decodeUnvalidated(v reflect.Value, p *typePlan) error {
+	if p.err != nil {
+		return p.err
 	}
 
-	// switch kind
-	switch v.Kind() {
+	switch p.kind {
 	case reflect.Pointer:
 		if v.IsNil() {
-			v.Set(reflect.New(v.Type().Elem()))
+			v.Set(reflect.New(p.elem.typ))
 		}
-		return d.decode(v.Elem())
+		if p.decodesAsEnum() {
+			if err := d.Enter(); err != nil {
+				return err
+			}
+			defer d.Leave()
+
+			if err := d.decodeEnum(v.Elem(), p.elem); err != nil || !p.elem.validator {
+				return err
+			}
+			return validate(v.Elem())
+		}
+		return d.decodeValue(v.Elem(), p.elem)
 
 	case reflect.Interface:
 		if v.IsNil() {
-			return 0, fmt.Errorf("cannot decode into nil interface")
+			return fmt.Errorf("cannot decode into nil interface")
 		}
-		return d.decode(v.Elem())
+		elem := v.Elem()
+		return d.decodeValue(elem, planFor(elem.Type()))
 
 	case reflect.Chan, reflect.Func, reflect.Uintptr, reflect.UnsafePointer:
 		// silently ignore
-		return 0, nil
-	default:
-		return d.decodeVanilla(v)
+		return nil
+	}
+
+	if (p.unmarshalerFrom || p.unmarshaler) && !v.CanAddr() {
+		return fmt.Errorf("cannot change value of kind %s", p.kind.String())
+	}
+
+	// UnmarshalerFrom
+	if p.unmarshalerFrom {
+		if err := d.Enter(); err != nil {
+			return err
+		}
+		defer d.Leave()
+
+		return v.Addr().Interface().(UnmarshalerFrom).UnmarshalBCSFrom(d)
 	}
+
+	// Unmarshaler
+	if p.unmarshaler {
+		_, err := v.Addr().Interface().(Unmarshaler).UnmarshalBCS((*decoderReader)(d))
+		return err
+	}
+
+	// Enum
+	if p.isEnum {
+		if err := d.Enter(); err != nil {
+			return err
+		}
+		defer d.Leave()
+
+		return d.decodeEnum(v, p)
+	}
+
+	return d.decodeVanilla(v, p)
 }
 
 // decodeVanilla decodes bool, ints, slice, struct, array, and string.
-func (d *Decoder) decodeVanilla(v reflect.Value) (int, error) {
-	kind := v.Kind()
+func (d *Decoder) decodeVanilla(v reflect.Value, p *typePlan) error {
+	kind := p.kind
 	if !v.CanSet() {
-		return 0, fmt.Errorf("cannot change value of kind %s", kind.String())
+		return fmt.Errorf("cannot change value of kind %s", kind.String())
 	}
 
 	switch kind {
 	case reflect.Bool:
-		t, n, err := d.readByte()
+		b, err := d.ReadBool()
 		if err != nil {
-			return n, err
+			return err
 		}
+		v.SetBool(b)
+		return nil
 
-		if t == 0 {
-			v.SetBool(false)
+	case reflect.Int8, reflect.Uint8:
+		x, err := d.ReadU8()
+		if err != nil {
+			return err
+		}
+		if kind == reflect.Int8 {
+			v.SetInt(int64(int8(x)))
 		} else {
-			v.SetBool(true)
+			v.SetUint(uint64(x))
 		}
-
-		return n, nil
-
-	case reflect.Int8, reflect.Uint8:
-		return 1, binary.Read(d.reader, binary.LittleEndian, v.Addr().Interface())
+		return nil
 	case reflect.Int16, reflect.Uint16:
-		return 2, binary.Read(d.reader, binary.LittleEndian, v.Addr().Interface())
+		x, err := d.ReadU16()
+		if err != nil {
+			return err
+		}
+		if kind == reflect.Int16 {
+			v.SetInt(int64(int16(x)))
+		} else {
+			v.SetUint(uint64(x))
+		}
+		return nil
 	case reflect.Int32, reflect.Uint32:
-		return 4, binary.Read(d.reader, binary.LittleEndian, v.Addr().Interface())
+		x, err := d.ReadU32()
+		if err != nil {
+			return err
+		}
+		if kind == reflect.Int32 {
+			v.SetInt(int64(int32(x)))
+		} else {
+			v.SetUint(uint64(x))
+		}
+		return nil
 	case reflect.Int64, reflect.Uint64:
-		return 8, binary.Read(d.reader, binary.LittleEndian, v.Addr().Interface())
+		x, err := d.ReadU64()
+		if err != nil {
+			return err
+		}
+		if kind == reflect.Int64 {
+			v.SetInt(int64(x))
+		} else {
+			v.SetUint(x)
+		}
+		return nil
 
 	case reflect.Struct:
-		return d.decodeStruct(v)
+		if err := d.Enter(); err != nil {
+			return err
+		}
+		defer d.Leave()
+
+		return d.decodeStruct(v, p)
 
 	case reflect.Slice:
-		sliceType := v.Type().Elem()
-		if sliceType.Kind() == reflect.Uint8 {
+		if p.elem.kind == reflect.Uint8 {
 			return d.decodeByteSlice(v)
 		}
 
-		return d.decodeSlice(v)
+		return d.decodeSlice(v, p)
 
 	case reflect.Array:
-		return d.decodeArray(v)
+		return d.decodeArray(v, p)
 
 	case reflect.String:
-		return d.decodeString(v)
+		s, err := d.ReadString()
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+		return nil
 
 	default:
-		return 0, fmt.Errorf("unsupported vanilla decoding type: %s", kind.String())
+		return fmt.Errorf("unsupported vanilla decoding type: %s", kind.String())
 	}
 }
 
-// decodeString
-func (d *Decoder) decodeString(v reflect.Value) (int, error) {
-	size, n, err := ULEB128Decode[int](d.reader)
-	if err != nil {
-		return n, err
-	}
+func (d *Decoder) decodeStruct(v reflect.Value, p *typePlan) error {
+	for _, f := range p.fields {
+		if f.isVersioned() && !f.inVersion(d.version) {
+			v.Field(f.index).SetZero()
+			continue
+		}
+		if f.tag.isNoCopy() && !d.zeroCopy {
+			d.zeroCopy = true
+			err := d.decodeField(v.Field(f.index), f)
+			d.zeroCopy = false
+			if err != nil {
+				return WithField(err, f.name)
+			}
+			continue
+		}
 
-	if size == 0 {
-		v.SetString("")
-		return n, nil
+		if err := d.decodeField(v.Field(f.index), f); err != nil {
+			return WithField(err, f.name)
+		}
 	}
 
-	// Allocate in chunks. `tmp` is guaranteed to allocate at most 2^32 / (1024*1024) = 4096 elements.
-	// This also avoids copying the data many times
-	tmp := make([][]byte, 0, (size+maxChunkSize-1)/maxChunkSize)
-	totalRead := 0
-
-	for totalRead < size {
-		// Determine how much to read in this iteration
-		remaining := size - totalRead
-		chunkSize := min(remaining, maxChunkSize)
-
-		tmp = append(tmp, make([]byte, chunkSize))
-
-		// Read the chunk
-		read, err := d.reader.Read(tmp[len(tmp)-1])
-		totalRead += read
+	return nil
+}
 
+func (d *Decoder) decodeField(field reflect.Value, f fieldPlan) error {
+	if f.tag.isOptional() {
+		isSome, err := d.ReadOptionTag()
 		if err != nil {
-			return n + totalRead, err
+			return err
 		}
-	}
-	// If there's only one chunk, no need to copy the data
-	if len(tmp) == 1 {
-		v.SetString(string(tmp[0]))
-	} else {
-		v.SetString(string(bytes.Join(tmp, nil)))
+		if !isSome {
+			field.Set(reflect.Zero(f.plan.typ))
+			return nil
+		}
+		elem, err := f.optionalElem()
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.New(elem.typ))
+		return d.decodeConstrained(field.Elem(), elem, f.constraints)
 	}
 
-	return n + totalRead, nil
+	return d.decodeConstrained(field, f.plan, f.constraints)
 }
 
-// readByte reads one byte from the input, error if no byte is read.
-func (d *Decoder) readByte() (byte, int, error) {
-	b := d.byteBuffer[:]
-	n, err := d.reader.Read(b)
-	if err != nil {
-		return 0, n, err
-	}
-	if n == 0 {
-		return 0, n, io.ErrUnexpectedEOF
+// decodeConstrained decodes the value of a field, and checks the constraints of the field before calling
+// [BCSValidator] of the value. The length of a vector with maxlen is checked before the vector is allocated.
+func (d *Decoder) decodeConstrained(v reflect.Value, p *typePlan, c *constraints) error {
+	if c == nil {
+		return d.decodeValue(v, p)
 	}
 
-	return b[0], n, nil
-}
-
-func (d *Decoder) decodeStruct(v reflect.Value) (int, error) {
-	t := v.Type()
-
-	var n int
+	for p.kind == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(p.elem.typ))
+		}
+		v, p = v.Elem(), p.elem
+	}
 
-fieldLoop:
-	for i := 0; i < v.NumField(); i++ {
-		field := v.Field(i)
-		if !field.CanInterface() {
-			continue fieldLoop
+	if c.maxLen < 0 {
+		if err := d.decodeUnvalidated(v, p); err != nil {
+			return err
 		}
-		tag, err := parseTagValue(t.Field(i).Tag.Get(tagName))
+	} else {
+		size, err := d.ReadLength()
 		if err != nil {
-			return n, err
+			return err
 		}
-
-		switch {
-		case tag.isIgnored(): // ignored
-			continue fieldLoop
-		case tag.isOptional(): // optional
-			isOptional, k, err := d.readByte()
-			n += k
-			if err != nil {
-				return n, err
-			}
-			if isOptional == 0 {
-				field.Set(reflect.Zero(field.Type()))
-			} else {
-				field.Set(reflect.New(field.Type().Elem()))
-				k, err := d.decode(field.Elem())
-				n += k
-				if err != nil {
-					return n, err
-				}
-			}
-		default:
-			k, err := d.decode(field)
-			n += k
-			if err != nil {
-				return n, err
-			}
+		if size > c.maxLen {
+			return &ValidationError{Err: fmt.Errorf("length %d exceeds maxlen %d", size, c.maxLen)}
+		}
+		if err := d.decodeVector(v, p, size); err != nil {
+			return err
 		}
 	}
 
-	return n, nil
-}
-
-func (d *Decoder) decodeEnum(v reflect.Value) (int, error) {
-	if v.Kind() != reflect.Struct {
-		return 0, fmt.Errorf("only support struct for Enum, got %s", v.Kind().String())
-	}
-	enumId, n, err := ULEB128Decode[int](d.reader)
-	if err != nil {
-		return n, err
+	if err := c.check(v); err != nil {
+		return err
 	}
-
-	if enumId >= v.NumField() {
-		return n, fmt.Errorf("enum field %d is out of range", enumId)
+	if p.validator {
+		return validate(v)
 	}
 
-	field := v.Field(enumId)
+	return nil
+}
 
-	// Initialize nil pointer fields before decoding
-	if field.Kind() == reflect.Ptr && field.IsNil() {
-		field.Set(reflect.New(field.Type().Elem()))
+// decodeVector decodes a string, []byte, or slice whose length is already read.
+func (d *Decoder) decodeVector(v reflect.Value, p *typePlan, size int) error {
+	if p.kind == reflect.Slice && p.elem.kind != reflect.Uint8 {
+		return d.decodeSliceElements(v, p, size)
 	}
 
-	k, err := d.decode(field)
-	n += k
+	b, err := d.readBytes(size)
+	if err != nil {
+		return err
+	}
+	if p.kind == reflect.String {
+		v.SetString(unsafe.String(unsafe.SliceData(b), len(b)))
+	} else {
+		v.SetBytes(b)
+	}
 
-	return n, err
+	return nil
 }
 
-func (d *Decoder) decodeByteSlice(v reflect.Value) (int, error) {
-	size, n, err := ULEB128Decode[int](d.reader)
+func (d *Decoder) decodeEnum(v reflect.Value, p *typePlan) error {
+	if p.kind != reflect.Struct {
+		return fmt.Errorf("only support struct for Enum, got %s", p.kind.String())
+	}
+	enumId, err := d.ReadVariant()
 	if err != nil {
-		return n, err
+		return err
 	}
 
-	if size == 0 {
-		return n, nil
+	if int(enumId) >= len(p.variants) {
+		return fmt.Errorf("enum field %d is out of range", enumId)
 	}
 
-	// Allocate in chunks. `tmp` is guaranteed to allocate at most 2^32 / (1024*1024) = 4096 elements.
-	// This also avoids copying the data many times
-	tmp := make([][]byte, 0, (size+maxChunkSize-1)/maxChunkSize)
-	totalRead := 0
-
-	for totalRead < size {
-		// Determine how much to read in this iteration
-		remaining := size - totalRead
-		chunkSize := min(remaining, maxChunkSize)
-
-		tmp = append(tmp, make([]byte, chunkSize))
+	f := p.variants[enumId]
+	if f == nil {
+		return fmt.Errorf("enum field %d is unexported or ignored", enumId)
+	}
 
-		// Read the chunk
-		read, err := d.reader.Read(tmp[len(tmp)-1])
-		totalRead += read
+	return WithField(d.decodeConstrained(v.Field(f.index), f.plan, f.constraints), f.name)
+}
 
-		if err != nil {
-			return n + totalRead, err
-		}
+func (d *Decoder) decodeByteSlice(v reflect.Value) error {
+	b, err := d.ReadBytes()
+	if err != nil {
+		return err
 	}
 
-	// If there's only one chunk, no need to do an extra copy inside Join
-	if len(tmp) == 1 {
-		v.SetBytes(tmp[0])
-	} else {
-		v.SetBytes(bytes.Join(tmp, nil))
-	}
+	v.SetBytes(b)
 
-	return n + totalRead, nil
+	return nil
 }
 
-func (d *Decoder) decodeArray(v reflect.Value) (int, error) {
+func (d *Decoder) decodeArray(v reflect.Value, p *typePlan) error {
+	if p.plain {
+		return d.decodePlainArray(v, p)
+	}
+
 	size := v.Len()
-	t := v.Type()
-	elementType := t.Elem()
-
-	var n int
-	if elementType.Kind() == reflect.Pointer {
-		for i := 0; i < size; i++ {
-			idx := reflect.New(elementType.Elem())
-			k, err := d.decode(idx.Elem())
-			n += k
-			if err != nil {
-				return n, err
-			}
-			v.Index(i).Set(idx)
-		}
-	} else {
-		for i := 0; i < size; i++ {
-			idx := reflect.New(elementType)
-			k, err := d.decode(idx.Elem())
-			n += k
-			if err != nil {
-				return n, err
-			}
-			v.Index(i).Set(idx.Elem())
+	for i := 0; i < size; i++ {
+		if err := d.decodeValue(v.Index(i), p.elem); err != nil {
+			return WithIndex(err, i)
 		}
 	}
 
-	return n, nil
+	return nil
 }
 
-func (d *Decoder) decodeSlice(v reflect.Value) (int, error) {
+// decodeSlice decodes a vector into the slice, reusing the capacity of the slice if it is large enough.
+// The preallocation is bounded, see [Decoder.sliceCapacity], and the slice grows geometrically
+// as the elements are decoded, so a large declared length with a short input won't cause a large allocation.
+func (d *Decoder) decodeSlice(v reflect.Value, p *typePlan) error {
 	// get the length of the slice.
-	size, n, err := ULEB128Decode[int](d.reader)
+	size, err := d.ReadLength()
 	if err != nil {
-		return n, err
+		return err
 	}
 
-	// element type of the slice
-	elementType := v.Type().Elem()
+	return d.decodeSliceElements(v, p, size)
+}
 
-	// Use a linked list to hold elements
-	// This avoids pre-allocating very large buffer when elements are large
-	// or the number of elements is large
-	l := list.New()
+// decodeSliceElements decodes the size elements of a vector into the slice after the length is read.
+func (d *Decoder) decodeSliceElements(v reflect.Value, p *typePlan, size int) error {
+	if p.elem.plain {
+		return d.decodePlainSlice(v, p, size)
+	}
 
-	if elementType.Kind() == reflect.Pointer {
-		for i := 0; i < size; i++ {
-			ind := reflect.New(elementType.Elem())
-			k, err := d.decode(ind.Elem())
-			n += k
-			if err != nil {
-				return n, err
-			}
-			l.PushBack(ind)
-		}
+	n, err := d.sliceCapacity(size, p.elem)
+	if err != nil {
+		return err
+	}
+	if v.IsNil() || v.Cap() < n {
+		v.Set(reflect.MakeSlice(v.Type(), 0, n))
 	} else {
-		for i := 0; i < size; i++ {
-			ind := reflect.New(elementType)
-			k, err := d.decode(ind.Elem())
-			n += k
-			if err != nil {
-				return n, err
-			}
-			l.PushBack(ind)
-		}
+		v.SetLen(0)
 	}
 
-	// Now it is okay to allocate a slice of full size
-	// since we would have returned early with EoF if the
-	// input was smaller than the declare size
-	v.Set(reflect.MakeSlice(v.Type(), size, size))
-	for i, e := 0, l.Front(); e != nil; i, e = i+1, e.Next() {
-		if elementType.Kind() == reflect.Pointer {
-			v.Index(i).Set(e.Value.(reflect.Value))
-		} else {
-			v.Index(i).Set(e.Value.(reflect.Value).Elem())
+	for i := 0; i < size; i++ {
+		if i == v.Cap() {
+			v.Grow(min(size-i, i))
 		}
+		v.SetLen(i + 1)
+		elem := v.Index(i)
+		// the element may hold the value decoded previously.
+		elem.SetZero()
+		if err := d.decodeValue(elem, p.elem); err != nil {
+			return WithIndex(err, i)
+		}
+	}
+
+	return nil
+}
+
+// sliceCapacity returns the capacity to preallocate for a slice of size elements of plan p,
+// which is at most the number of elements fitting in [maxChunkSize] bytes of memory.
+// For in memory input, it errors if the remaining input is too short for the elements.
+func (d *Decoder) sliceCapacity(size int, p *typePlan) (int, error) {
+	if d.data != nil && uint64(size)*uint64(p.minSize) > uint64(len(d.data)-d.offset) {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	if memSize := int(p.typ.Size()); memSize > 0 {
+		return min(size, max(1, maxChunkSize/memSize)), nil
 	}
 
-	return n, nil
+	return size, nil
 }
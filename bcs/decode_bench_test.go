@@ -106,4 +106,41 @@ func BenchmarkDecodeByteSlice(b *testing.B) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func BenchmarkDecodeZeroCopy(b *testing.B) {
+	type TestStruct struct {
+		Value int32
+		Name  string
+		Data  []byte
+	}
+
+	testData := make([]TestStruct, 256)
+	for i := range testData {
+		testData[i] = TestStruct{
+			Value: int32(i),
+			Name:  fmt.Sprintf("item_%d", i),
+			Data:  make([]byte, 64),
+		}
+	}
+
+	encoded, err := bcs.Marshal(testData)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, zeroCopy := range []bool{false, true} {
+		b.Run(fmt.Sprintf("zero_copy_%t", zeroCopy), func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				var result []TestStruct
+				d := bcs.NewBytesDecoder(encoded)
+				d.SetZeroCopy(zeroCopy)
+				if _, err := d.Decode(&result); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,363 @@
+package bcs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"unsafe"
+)
+
+// decoderReader exposes the [Decoder] as an [io.Reader] to [Unmarshaler],
+// so the bytes read by customized unmarshalers are tracked by the decoder.
+type decoderReader Decoder
+
+func (r *decoderReader) Read(p []byte) (int, error) {
+	d := (*Decoder)(r)
+	if d.data != nil {
+		if d.offset >= len(d.data) && len(p) > 0 {
+			return 0, io.EOF
+		}
+		n := copy(p, d.data[d.offset:])
+		d.offset += n
+		return n, nil
+	}
+
+	n, err := d.reader.Read(p)
+	d.offset += n
+	if d.capturing > 0 {
+		d.captured = append(d.captured, p[:n]...)
+	}
+	return n, err
+}
+
+// startCapture starts recording the bytes read from the input. Captures can be nested,
+// and each call must be paired with a call to stopCapture, which receives the returned position.
+func (d *Decoder) startCapture() int {
+	if d.data != nil {
+		return d.offset
+	}
+
+	d.capturing++
+	return len(d.captured)
+}
+
+// stopCapture stops the capture started at position start, and returns a copy of the bytes read since then.
+// For in memory input, the bytes alias the input if zero copy is on.
+func (d *Decoder) stopCapture(start int) []byte {
+	if d.data != nil {
+		return d.copyBytes(d.data[start:d.offset])
+	}
+
+	r := append([]byte{}, d.captured[start:]...)
+	d.capturing--
+	if d.capturing == 0 {
+		d.captured = d.captured[:0]
+	}
+
+	return r
+}
+
+// Offset returns the number of bytes the decoder has consumed so far.
+func (d *Decoder) Offset() int {
+	return d.offset
+}
+
+// next returns the next n bytes of the input, error if the input doesn't have enough bytes.
+//
+// For in memory input, the returned bytes alias the input. Otherwise, they are read into the scratch buffer,
+// so n must be no larger than the scratch buffer, and the bytes are only valid until the next read.
+func (d *Decoder) next(n int) ([]byte, error) {
+	if d.data == nil {
+		b := d.scratch[:n]
+		return b, d.readFull(b)
+	}
+
+	remaining := len(d.data) - d.offset
+	if remaining < n {
+		if remaining == 0 {
+			return nil, io.EOF
+		}
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	b := d.data[d.offset : d.offset+n : d.offset+n]
+	d.offset += n
+
+	return b, nil
+}
+
+// copyBytes copies the bytes from in memory input, unless zero copy is on.
+func (d *Decoder) copyBytes(b []byte) []byte {
+	if d.zeroCopy {
+		return b[:len(b):len(b)]
+	}
+
+	return append(make([]byte, 0, len(b)), b...)
+}
+
+// readFull fills b from the input, error if the input doesn't have enough bytes.
+func (d *Decoder) readFull(b []byte) error {
+	if d.data != nil {
+		src, err := d.next(len(b))
+		copy(b, src)
+		return err
+	}
+
+	_, err := io.ReadFull((*decoderReader)(d), b)
+	return err
+}
+
+// discard skips n bytes from the input, error if the input doesn't have enough bytes.
+func (d *Decoder) discard(n int) error {
+	if d.data != nil {
+		_, err := d.next(n)
+		return err
+	}
+
+	k, err := io.CopyN(io.Discard, (*decoderReader)(d), int64(n))
+	if err == io.EOF && k > 0 {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
+
+// ReadU8 reads a u8.
+func (d *Decoder) ReadU8() (uint8, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return 0, err
+	}
+
+	return b[0], nil
+}
+
+// ReadU16 reads a little endian u16.
+func (d *Decoder) ReadU16() (uint16, error) {
+	b, err := d.next(2)
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint16(b), nil
+}
+
+// ReadU32 reads a little endian u32.
+func (d *Decoder) ReadU32() (uint32, error) {
+	b, err := d.next(4)
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+// ReadU64 reads a little endian u64.
+func (d *Decoder) ReadU64() (uint64, error) {
+	b, err := d.next(8)
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+// ReadU128 reads a little endian u128.
+func (d *Decoder) ReadU128() (Uint128, error) {
+	b, err := d.next(16)
+	if err != nil {
+		return Uint128{}, err
+	}
+
+	return Uint128{
+		lo: binary.LittleEndian.Uint64(b[0:8]),
+		hi: binary.LittleEndian.Uint64(b[8:16]),
+	}, nil
+}
+
+// ReadU256 reads a little endian u256.
+func (d *Decoder) ReadU256() (*big.Int, error) {
+	b := d.scratch[:32]
+	if err := d.readFull(b); err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
+
+// ReadBool reads a bool. Only 0 and 1 are valid, and other values error.
+func (d *Decoder) ReadBool() (bool, error) {
+	b, err := d.ReadU8()
+	if err != nil {
+		return false, err
+	}
+
+	switch b {
+	case 0:
+		return false, nil
+	case 1:
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid bool value: %d", b)
+	}
+}
+
+// ReadULEB128 reads an ULEB128 encoded integer. The encoding must be minimal and the value must fit in a u32.
+func (d *Decoder) ReadULEB128() (uint32, error) {
+	if d.data == nil {
+		v, _, err := ULEB128Decode[uint32]((*decoderReader)(d))
+		return v, err
+	}
+
+	if d.offset >= len(d.data) {
+		return 0, io.EOF
+	}
+	// fast path for the most common case of a single byte.
+	if d.data[d.offset] < 0x80 {
+		v := d.data[d.offset]
+		d.offset++
+		return uint32(v), nil
+	}
+
+	v, n, err := ULEB128Decode[uint32](bytes.NewReader(d.data[d.offset:]))
+	d.offset += n
+	return v, err
+}
+
+// ReadLength reads the length of a vector, which is written as an ULEB128.
+// Lengths that overflow int, which can happen on 32-bit platforms, are errors.
+func (d *Decoder) ReadLength() (int, error) {
+	v, err := d.ReadULEB128()
+	if err != nil {
+		return 0, err
+	}
+	if uint64(v) > math.MaxInt {
+		return 0, fmt.Errorf("length %d overflows int", v)
+	}
+
+	return int(v), nil
+}
+
+// ReadFixedBytes fills b with the bytes from the input, without reading a length prefix first.
+func (d *Decoder) ReadFixedBytes(b []byte) error {
+	return d.readFull(b)
+}
+
+// ReadBytes reads a vector<u8>. An empty vector is returned as an empty but non-nil slice.
+//
+// The bytes are allocated in chunks as they are read, so a large declared length
+// doesn't cause a large allocation before the input is confirmed to contain that many bytes.
+func (d *Decoder) ReadBytes() ([]byte, error) {
+	size, err := d.ReadLength()
+	if err != nil {
+		return nil, err
+	}
+
+	return d.readBytes(size)
+}
+
+// ReadBytesOfLength reads the bytes of a vector<u8> whose length is already read with [Decoder.ReadLength],
+// such as after the length is checked against a limit. Like [Decoder.ReadBytes], the bytes alias the input
+// if zero copy is on for in memory input.
+func (d *Decoder) ReadBytesOfLength(size int) ([]byte, error) {
+	return d.readBytes(size)
+}
+
+// readBytes reads the size bytes of a vector<u8> after its length.
+func (d *Decoder) readBytes(size int) ([]byte, error) {
+	if d.data != nil {
+		b, err := d.next(size)
+		if err != nil {
+			return nil, err
+		}
+		return d.copyBytes(b), nil
+	}
+
+	return d.readChunked(size)
+}
+
+// readChunked reads size bytes. Allocate in chunks. `tmp` is guaranteed to allocate at most 2^32 / (1024*1024) = 4096 elements.
+// This also avoids copying the data many times
+func (d *Decoder) readChunked(size int) ([]byte, error) {
+	if size == 0 {
+		return []byte{}, nil
+	}
+
+	tmp := make([][]byte, 0, (size+maxChunkSize-1)/maxChunkSize)
+	totalRead := 0
+
+	for totalRead < size {
+		// Determine how much to read in this iteration
+		chunkSize := min(size-totalRead, maxChunkSize)
+
+		tmp = append(tmp, make([]byte, chunkSize))
+
+		// Read the chunk
+		if err := d.readFull(tmp[len(tmp)-1]); err != nil {
+			return nil, err
+		}
+		totalRead += chunkSize
+	}
+
+	// If there's only one chunk, no need to do an extra copy inside Join
+	if len(tmp) == 1 {
+		return tmp[0], nil
+	}
+
+	return bytes.Join(tmp, nil), nil
+}
+
+// ReadString reads a string, which is encoded as a vector<u8>.
+//
+// If zero copy is on for in memory input, the string aliases the input.
+func (d *Decoder) ReadString() (string, error) {
+	b, err := d.ReadBytes()
+	if err != nil {
+		return "", err
+	}
+
+	// b is either newly allocated, or aliases the input under zero copy,
+	// so it can back the string without another copy.
+	return unsafe.String(unsafe.SliceData(b), len(b)), nil
+}
+
+// ReadStringOfLength reads the bytes of a string whose length is already read with [Decoder.ReadLength],
+// see [Decoder.ReadBytesOfLength].
+func (d *Decoder) ReadStringOfLength(size int) (string, error) {
+	b, err := d.readBytes(size)
+	if err != nil {
+		return "", err
+	}
+
+	return unsafe.String(unsafe.SliceData(b), len(b)), nil
+}
+
+// ReadOptionTag reads the tag of an option, returns true if the value is present.
+// If true is returned, the value should be read right after. Only 0 and 1 are valid, and other values error.
+func (d *Decoder) ReadOptionTag() (bool, error) {
+	b, err := d.ReadU8()
+	if err != nil {
+		return false, err
+	}
+
+	switch b {
+	case 0:
+		return false, nil
+	case 1:
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid option tag: %d", b)
+	}
+}
+
+// ReadVariant reads the index of an enum variant. The content of the variant should be read right after.
+func (d *Decoder) ReadVariant() (uint32, error) {
+	return d.ReadULEB128()
+}
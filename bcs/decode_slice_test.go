@@ -0,0 +1,62 @@
+package bcs_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+func TestDecodeSlice_reuse(t *testing.T) {
+	first := []EnumExample{{V0: new(uint8)}, {V2: new(uint32)}}
+	second := []EnumExample{{V2: new(uint32)}}
+
+	result := make([]EnumExample, 0, 4)
+	backing := &result[:1][0]
+
+	if err := bcs.UnmarshalAll(bcs.MustMarshal(first), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 2 || &result[0] != backing {
+		t.Fatalf("capacity is not reused, len: %d", len(result))
+	}
+
+	if err := bcs.UnmarshalAll(bcs.MustMarshal(second), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 1 || &result[0] != backing {
+		t.Fatalf("capacity is not reused, len: %d", len(result))
+	}
+	// the variant decoded previously must be cleared.
+	if result[0].V0 != nil || result[0].V2 == nil {
+		t.Fatalf("unexpected element: %#v", result[0])
+	}
+
+	ints := make([]uint32, 1, 8)
+	if _, err := bcs.NewDecoder(bytes.NewReader(bcs.MustMarshal([]uint32{1, 2, 3}))).Decode(&ints); err != nil {
+		t.Fatal(err)
+	}
+	if len(ints) != 3 || cap(ints) != 8 || ints[2] != 3 {
+		t.Fatalf("capacity is not reused: %v", ints)
+	}
+}
+
+func TestDecodeSlice_largeLength(t *testing.T) {
+	// declares 2^31-1 elements, each of which is at least 2 bytes.
+	data := []byte{0xff, 0xff, 0xff, 0xff, 0x07, 1, 0, 2}
+
+	var fromBytes []MyStruct
+	if _, err := bcs.Unmarshal(data, &fromBytes); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("want unexpected EOF, got: %v", err)
+	}
+
+	var fromReader []MyStruct
+	if _, err := bcs.NewDecoder(bytes.NewReader(data)).Decode(&fromReader); err == nil {
+		t.Fatal("want error for truncated input")
+	}
+	if cap(fromReader) > 1024*1024 {
+		t.Fatalf("preallocated %d elements", cap(fromReader))
+	}
+}
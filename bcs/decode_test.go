@@ -1,6 +1,7 @@
 package bcs_test
 
 import (
+	"bytes"
 	"fmt"
 	"slices"
 	"strings"
@@ -195,6 +196,25 @@ func TestEmptyByteSlice(t *testing.T) {
 	if n != 1 {
 		t.Errorf("want parsed length 1")
 	}
+
+	// empty vectors are decoded as empty but non-nil slices, replacing the existing values.
+	for name, d := range map[string]*bcs.Decoder{
+		"bytes":  bcs.NewBytesDecoder(encoded),
+		"reader": bcs.NewDecoder(bytes.NewReader(encoded)),
+		"zero copy": func() *bcs.Decoder {
+			d := bcs.NewBytesDecoder(encoded)
+			d.SetZeroCopy(true)
+			return d
+		}(),
+	} {
+		v := []byte{1, 2}
+		if _, err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if v == nil || len(v) != 0 {
+			t.Errorf("%s: want empty non-nil slice, got %#v", name, v)
+		}
+	}
 }
 
 func TestLargeDeclaredSize(t *testing.T) {
@@ -210,3 +230,13 @@ func TestLargeDeclaredSize(t *testing.T) {
 		t.Fatalf("expected unmarshaling to fail with insufficient data")
 	}
 }
+
+func TestUnmarshalIntoInterfaceHoldingValue(t *testing.T) {
+	data := make([]byte, 16)
+	for _, v := range []any{bcs.Uint128{}, CustomUnmarshal{}} {
+		x := v
+		if _, err := bcs.Unmarshal(data, &x); err == nil || !strings.Contains(err.Error(), "cannot change value") {
+			t.Errorf("%T: want cannot change value error, got %v", v, err)
+		}
+	}
+}
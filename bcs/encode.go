@@ -2,139 +2,297 @@ package bcs
 
 import (
 	"bytes"
-	"encoding/binary"
 	"fmt"
 	"io"
 	"reflect"
 )
 
 // Encoder takes an [io.Writer] and encodes value into it.
+//
+// Besides encoding values with [Encoder.Encode], the encoder exposes methods to write the
+// primitives of bcs, such as [Encoder.WriteU64] or [Encoder.WriteULEB128], which can be
+// used to implement customized marshalers.
+//
+// The output is buffered while a value is encoded, and written to the [io.Writer]
+// when [Encoder.Encode] returns or the buffer is full, instead of once per primitive.
 type Encoder struct {
-	w io.Writer
+	// w is nil if the output is kept in buf.
+	w   io.Writer
+	buf []byte
+	// counting indicates only the size of the output is computed, see [Size].
+	counting bool
+	count    int
+	// encoding is the number of nested calls to encode, the buffer is flushed when it goes back to 0.
+	encoding int
+	scratch  [32]byte
+	depth    int
+	maxDepth int
+	version  int
+	// vectors are the vectors started by [Encoder.BeginVector] and not yet ended.
+	vectors []vectorFrame
 }
 
+// encoderBufferSize is the size of the buffer at which the output is flushed to the [io.Writer].
+const encoderBufferSize = 4096
+
 // NewEncoder creates a new [Encoder] from an [io.Writer]
 func NewEncoder(w io.Writer) *Encoder {
 	return &Encoder{
-		w: w,
+		w:        w,
+		maxDepth: DefaultMaxDepth,
+	}
+}
+
+// newBytesEncoder creates an [Encoder] appending the output to dst.
+func newBytesEncoder(dst []byte) *Encoder {
+	return &Encoder{
+		buf:      dst,
+		maxDepth: DefaultMaxDepth,
+	}
+}
+
+// flush writes the buffered output to the writer.
+func (e *Encoder) flush() error {
+	if e.w == nil || len(e.buf) == 0 {
+		return nil
+	}
+
+	_, err := e.w.Write(e.buf)
+	e.buf = e.buf[:0]
+
+	return err
+}
+
+// SetMaxDepth sets the max depth of nested structs, enums, and customized marshalers
+// the encoder will go into. Non-positive value removes the limit.
+func (e *Encoder) SetMaxDepth(maxDepth int) {
+	e.maxDepth = maxDepth
+}
+
+// Enter increases the depth of nested values, and errors if the max depth is exceeded.
+// Call [Encoder.Leave] after the nested value is encoded.
+//
+// Encoding structs, enums, and [MarshalerTo] through [Encoder.Encode] already counts the depth.
+// Implementations of [MarshalerTo] calling each other directly, such as the code generated
+// by bcsgen, can use this to keep the protection against cyclic or deeply nested values.
+func (e *Encoder) Enter() error {
+	e.depth++
+	if e.maxDepth > 0 && e.depth > e.maxDepth {
+		e.depth--
+		return fmt.Errorf("exceeded max depth %d", e.maxDepth)
 	}
+
+	return nil
+}
+
+// Leave decreases the depth increased by [Encoder.Enter].
+func (e *Encoder) Leave() {
+	e.depth--
 }
 
 // Encode a value v into the encoder.
 //
+//   - If the value is [MarshalerTo], the corresponding
+//     MarshalBCSTo implementation will be called.
 //   - If the value is [Marshaler], the corresponding
 //     MarshalBCS implementation will be called.
 //   - If the value is [Enum], it will be special handled for [Enum].
 func (e *Encoder) Encode(v any) error {
-	return e.encode(reflect.ValueOf(v))
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return fmt.Errorf("cannot encode nil")
+	}
+
+	return e.encodeTop(rv, planFor(rv.Type()))
 }
 
-// encode a value
-func (e *Encoder) encode(v reflect.Value) error {
-	// if v not CanInterface,
-	// this value is an unexported value, skip it.
-	if !v.CanInterface() {
-		return nil
+// encodeTop encodes a value, and flushes the output if this is not nested inside another call.
+// The buffered output is discarded if there is an error.
+func (e *Encoder) encodeTop(v reflect.Value, p *typePlan) error {
+	if err := e.beginTop(); err != nil {
+		return err
 	}
 
-	// test for the two interfaces we defined.
-	// 1. Marshaler
-	// 2. Enum.
-	i := v.Interface()
-	if m, ismarshaler := i.(Marshaler); ismarshaler {
-		bytes, err := m.MarshalBCS()
-		if err != nil {
-			return err
-		}
+	return e.endTop(e.encodeValue(v, p))
+}
+
+// beginTop starts encoding a value with [Encoder.Encode] or a [Codec], which is counted as an element of
+// the vector started by [Encoder.BeginVector], and whose output is buffered until [Encoder.endTop].
+func (e *Encoder) beginTop() error {
+	if err := e.countElement(); err != nil {
+		return err
+	}
 
-		_, err = e.w.Write(bytes)
+	e.encoding++
 
+	return nil
+}
+
+// endTop ends encoding the value started by [Encoder.beginTop] with the error of encoding it, and flushes
+// the output if this is not nested inside another call. The buffered output is discarded if there is an error.
+func (e *Encoder) endTop(err error) error {
+	e.encoding--
+
+	if e.encoding > 0 || e.w == nil {
 		return err
 	}
-	if _, isenum := i.(Enum); isenum {
-		return e.encodeEnum(reflect.Indirect(v))
+	if err != nil {
+		e.buf = e.buf[:0]
+		return err
 	}
 
-	kind := v.Kind()
+	return e.flush()
+}
+
+// encodeValue encodes a value following the plan of its type.
+func (e *Encoder) encodeValue(v reflect.Value, p *typePlan) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	// if v is nil pointer, use the zero value for v.
+	// we don't check for optional flag here.
+	// that should be checked when the container struct is encoded
+	// if this pointer is contained in a struct.
+	if p.kind == reflect.Pointer && v.IsNil() {
+		return e.encodeValue(reflect.Zero(p.elem.typ), p.elem)
+	}
+
+	// pointer receivers are always used, on a copy of the value if it is not addressable,
+	// so the output doesn't depend on whether the value is passed by pointer.
+	if (p.marshalerToAddr || p.marshalerAddr || p.preparerAddr) && !v.CanAddr() {
+		tmp := reflect.New(p.typ).Elem()
+		tmp.Set(v)
+		v = tmp
+	}
+
+	if p.preparer || p.preparerAddr {
+		if err := prepare(v, p); err != nil {
+			return err
+		}
+	}
+
+	// test for the interfaces we defined.
+	// 1. MarshalerTo
+	// 2. Marshaler
+	// 3. Enum.
+	switch {
+	case p.marshalerTo || p.marshalerToAddr:
+		if err := e.Enter(); err != nil {
+			return err
+		}
+		defer e.Leave()
+
+		if p.marshalerToAddr {
+			v = v.Addr()
+		}
+		return v.Interface().(MarshalerTo).MarshalBCSTo(e)
+
+	case p.marshaler || p.marshalerAddr:
+		if p.marshalerAddr {
+			v = v.Addr()
+		}
+		bytes, err := v.Interface().(Marshaler).MarshalBCS()
+		if err != nil {
+			return err
+		}
+
+		return e.WriteFixedBytes(bytes)
+
+	case p.isEnum:
+		if err := e.Enter(); err != nil {
+			return err
+		}
+		defer e.Leave()
+
+		return e.encodeEnum(reflect.Indirect(v), p.enumPlan())
+	}
 
-	switch kind {
-	case reflect.Bool, // boolean
-		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, // all the ints
-		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64: // all the uints
-		// use little endian to encode those.
-		return binary.Write(e.w, binary.LittleEndian, v.Interface())
+	switch p.kind {
+	case reflect.Bool:
+		return e.WriteBool(v.Bool())
+	case reflect.Int8:
+		return e.WriteU8(uint8(v.Int()))
+	case reflect.Int16:
+		return e.WriteU16(uint16(v.Int()))
+	case reflect.Int32:
+		return e.WriteU32(uint32(v.Int()))
+	case reflect.Int64:
+		return e.WriteU64(uint64(v.Int()))
+	case reflect.Uint8:
+		return e.WriteU8(uint8(v.Uint()))
+	case reflect.Uint16:
+		return e.WriteU16(uint16(v.Uint()))
+	case reflect.Uint32:
+		return e.WriteU32(uint32(v.Uint()))
+	case reflect.Uint64:
+		return e.WriteU64(v.Uint())
 
 	case reflect.Pointer: // pointer
-		// if v is nil pointer, use the zero value for v.
-		// we don't check for optional flag here.
-		// that should be checked when the container struct is encoded
-		// if this pointer is contained in a struct.
-		return e.encode(reflect.Indirect(v))
+		return e.encodeValue(v.Elem(), p.elem)
 
 	case reflect.Interface:
-		return e.encode(v.Elem())
+		if v.IsNil() {
+			return fmt.Errorf("cannot encode nil interface %s", p.typ.String())
+		}
+		elem := v.Elem()
+		return e.encodeValue(elem, planFor(elem.Type()))
 
 	case reflect.Slice: // slices
 		// check if the element is uint8 or byteslice
-		if byteSlice, ok := (v.Interface()).([]byte); ok {
-			return e.encodeByteSlice(byteSlice)
+		if p.elem.kind == reflect.Uint8 {
+			return e.WriteBytes(v.Bytes())
 		}
-		return e.encodeSlice(v)
+		return e.encodeSlice(v, p)
 
 	case reflect.Array: // encode array
-		return e.encodeArray(v)
+		return e.encodeArray(v, p)
 
 	case reflect.String:
-		str := []byte(v.String())
-		return e.encodeByteSlice(str)
+		return e.WriteString(v.String())
 
 	case reflect.Struct:
-		return e.encodeStruct(v)
+		if err := e.Enter(); err != nil {
+			return err
+		}
+		defer e.Leave()
+
+		return e.encodeStruct(v, p)
 
 	case reflect.Chan, reflect.Func, reflect.Uintptr, reflect.UnsafePointer: // channel, func, pointers
 		return nil
 
 	default:
-		return fmt.Errorf("unsupported kind: %s, consider make the field ignored by using - tag or provide a customized Marshaler implementation", kind.String())
+		return fmt.Errorf("unsupported kind: %s, consider make the field ignored by using - tag or provide a customized Marshaler implementation", p.kind.String())
 	}
 }
 
 // encodeEnum encodes an [Enum]
-func (e *Encoder) encodeEnum(v reflect.Value) error {
-	t := v.Type()
-
-	for i := 0; i < v.NumField(); i++ {
-		field := v.Field(i)
-		// ignore fields that are not exported
-		if !field.CanInterface() {
-			continue
-		}
+func (e *Encoder) encodeEnum(v reflect.Value, p *typePlan) error {
+	if p.kind != reflect.Struct {
+		return fmt.Errorf("only support struct for Enum, got %s", p.kind.String())
+	}
 
-		fieldType := t.Field(i)
-		// check the tag
-		tag, err := parseTagValue(fieldType.Tag.Get(tagName))
-		if err != nil {
-			return err
-		}
-		if tag.isIgnored() {
-			continue
+	for _, f := range p.fields {
+		field := v.Field(f.index)
+		if f.constraints != nil {
+			if err := f.constraints.check(field); err != nil {
+				return WithField(err, f.name)
+			}
 		}
-		fieldKind := field.Kind()
+		fieldKind := f.plan.kind
 		if fieldKind != reflect.Pointer && fieldKind != reflect.Interface {
 			return fmt.Errorf("enum only supports fields that are either pointers or interfaces, unless they are ignored")
 		}
 		if !field.IsNil() {
-			ie, err := ULEB128Encode(i)
-			if err != nil {
-				return err
-			}
-			if _, err := e.w.Write(ie); err != nil {
+			if err := e.WriteVariant(uint32(f.index)); err != nil {
 				return err
 			}
 			if fieldKind == reflect.Pointer {
-				return e.encode(reflect.Indirect(field))
+				return WithField(e.encodeValue(field.Elem(), f.plan.elem), f.name)
 			} else {
-				return e.encode(v)
+				elem := field.Elem()
+				return WithField(e.encodeValue(elem, planFor(elem.Type())), f.name)
 			}
 		}
 	}
@@ -142,92 +300,73 @@ func (e *Encoder) encodeEnum(v reflect.Value) error {
 	return fmt.Errorf("no field is set in the enum")
 }
 
-// encodeByteSlice is specialized since bytes those can be simply put into the output.
-func (e *Encoder) encodeByteSlice(b []byte) error {
-	le, err := ULEB128Encode(len(b))
-	if err != nil {
-		return err
-	}
-	if _, err := e.w.Write(le); err != nil {
-		return err
-	}
-
-	if _, err := e.w.Write(b); err != nil {
-		return err
+func (e *Encoder) encodeArray(v reflect.Value, p *typePlan) error {
+	if p.plain {
+		return e.encodePlainArray(v, p)
 	}
 
-	return nil
-}
-
-func (e *Encoder) encodeArray(v reflect.Value) error {
 	length := v.Len()
 	for i := 0; i < length; i++ {
-		if err := e.encode(v.Index(i)); err != nil {
-			return err
+		if err := e.encodeValue(v.Index(i), p.elem); err != nil {
+			return WithIndex(err, i)
 		}
 	}
 
 	return nil
 }
 
-func (e *Encoder) encodeSlice(v reflect.Value) error {
-	length := v.Len()
-	le, err := ULEB128Encode(length)
-	if err != nil {
-		return err
+func (e *Encoder) encodeSlice(v reflect.Value, p *typePlan) error {
+	if p.elem.plain {
+		return e.encodePlainSlice(v, p)
 	}
-	if _, err := e.w.Write(le); err != nil {
+
+	length := v.Len()
+	if err := e.WriteLength(length); err != nil {
 		return err
 	}
 
 	for i := 0; i < length; i++ {
-		if err := e.encode(v.Index(i)); err != nil {
-			return err
+		if err := e.encodeValue(v.Index(i), p.elem); err != nil {
+			return WithIndex(err, i)
 		}
 	}
 
 	return nil
 }
 
-func (e *Encoder) encodeStruct(v reflect.Value) error {
-	t := v.Type()
-
-	for i := 0; i < v.NumField(); i++ {
-		field := v.Field(i)
-		// if a field is not exported, ignore
-		if !field.CanInterface() {
+func (e *Encoder) encodeStruct(v reflect.Value, p *typePlan) error {
+	for _, f := range p.fields {
+		if f.isVersioned() && !f.inVersion(e.version) {
 			continue
 		}
-		tag, err := parseTagValue(t.Field(i).Tag.Get(tagName))
-		if err != nil {
-			return err
+		field := v.Field(f.index)
+		if f.constraints != nil {
+			if err := f.constraints.check(field); err != nil {
+				return WithField(err, f.name)
+			}
 		}
-		switch {
-		case tag.isIgnored():
-			continue
-		case tag.isOptional():
-			if field.Kind() != reflect.Pointer && field.Kind() != reflect.Interface {
+		if f.tag.isOptional() {
+			if f.plan.kind != reflect.Pointer && f.plan.kind != reflect.Interface {
 				return fmt.Errorf("optional field can only be pointer or interface")
 			}
-			if field.IsNil() {
-				_, err := e.w.Write([]byte{0})
-				if err != nil {
-					return err
-				}
-			} else {
-				if _, err := e.w.Write([]byte{1}); err != nil {
-					return err
+			if err := e.WriteOptionTag(!field.IsNil()); err != nil {
+				return err
+			}
+			if !field.IsNil() {
+				elem := field.Elem()
+				elemPlan := f.plan.elem
+				if f.plan.kind == reflect.Interface {
+					elemPlan = planFor(elem.Type())
 				}
-				if err := e.encode(field.Elem()); err != nil {
-					return err
+				if err := e.encodeValue(elem, elemPlan); err != nil {
+					return WithField(err, f.name)
 				}
 			}
 			continue
-		default:
-			// finally
-			if err := e.encode(field); err != nil {
-				return err
-			}
+		}
+
+		if err := e.encodeValue(field, f.plan); err != nil {
+			return WithField(err, f.name)
 		}
 	}
 
@@ -242,6 +381,13 @@ func (e *Encoder) encodeStruct(v reflect.Value) error {
 //   - Use tag `optional` to indicate an optional value in rust.
 //     the field must be pointer or interface.
 //   - Use tag `-` to ignore fields.
+//   - Use tags `maxlen=n` for vectors and strings, `min=n` and `max=n` for integers, and `utf8` or `ascii`
+//     for strings and []byte to constrain the values. Values violating the constraints fail to encode and decode
+//     with [*ValidationError], and the length of a vector is checked before it is allocated when decoding.
+//   - Use tags `since=n` and `until=n` for fields added in version n or removed after version n, so one struct
+//     can encode and decode every version of its schema. The fields not in the version set by [Encoder.SetVersion]
+//     or [Decoder.SetVersion] are skipped. Marshal uses [LatestVersion], which has the fields added with `since`
+//     but not the fields removed with `until`.
 //   - Unexported fields are ignored.
 //
 // Note that bcs doesn't have schema, and field names are irrelevant. The fields
@@ -258,19 +404,71 @@ func (e *Encoder) encodeStruct(v reflect.Value) error {
 //
 // Channels, functions are silently ignored.
 //
-// During marshalling process, how v is marshalled depends on if v implemented [Marshaler] or [Enum]
-//  1. if [Marshaler], use "MarshalBCS" method.
-//  2. if not [Marshaler] but [Enum], use specialization for [Enum].
-//  3. otherwise standard process.
+// During marshalling process, how v is marshalled depends on if v implemented [MarshalerTo], [Marshaler] or [Enum]
+//  1. if [MarshalerTo], use "MarshalBCSTo" method.
+//  2. if [Marshaler], use "MarshalBCS" method.
+//  3. if not [Marshaler] but [Enum], use specialization for [Enum].
+//  4. otherwise standard process.
+//
+// Methods with pointer receivers are used for values too, on a copy of the value if it is not addressable,
+// so Marshal(v) and Marshal(&v) produce the same bytes.
+//
+// Structs, enums and customized marshalers can be nested at most [DefaultMaxDepth] deep.
 func Marshal(v any) ([]byte, error) {
-	var b bytes.Buffer
-	e := NewEncoder(&b)
+	return AppendMarshal(nil, v)
+}
+
+// AppendMarshal is like [Marshal], but appends the bcs bytes of v to dst and returns the extended slice.
+// On error, dst is returned with its original length.
+func AppendMarshal(dst []byte, v any) ([]byte, error) {
+	e := newBytesEncoder(dst)
+
+	if err := e.Encode(v); err != nil {
+		return dst[:len(dst)], err
+	}
+
+	return e.buf, nil
+}
+
+// MarshalTo is like [Marshal], but writes the bcs bytes of v into buf and returns the number of bytes written.
+// If buf is too small, an error wrapping [io.ErrShortBuffer] is returned, and use [Size] to find out the
+// required size.
+//
+// v is sized with [Size] before it is written, so buf is left untouched if it is too small or v fails to encode,
+// at the cost of encoding v twice.
+func MarshalTo(buf []byte, v any) (int, error) {
+	n, err := Size(v)
+	if err != nil {
+		return 0, err
+	}
+	if n > len(buf) {
+		return 0, fmt.Errorf("%w: %d bytes required, got %d", io.ErrShortBuffer, n, len(buf))
+	}
+
+	e := newBytesEncoder(buf[:0:n])
+	if err := e.Encode(v); err != nil {
+		return 0, err
+	}
+	if len(e.buf) != n {
+		return 0, fmt.Errorf("encoded %d bytes, different from the size %d", len(e.buf), n)
+	}
+
+	return n, nil
+}
+
+// Size returns the number of bytes of the bcs encoding of v, without keeping the encoded bytes,
+// similar to serialized_size of the rust implementation.
+//
+// Values implementing [Marshaler] are still marshalled to find out their sizes.
+func Size(v any) (int, error) {
+	e := newBytesEncoder(nil)
+	e.counting = true
 
 	if err := e.Encode(v); err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	return b.Bytes(), nil
+	return e.count, nil
 }
 
 type Option[T any] struct {
@@ -0,0 +1,125 @@
+package bcs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+)
+
+// write puts the bytes into the buffer, which is flushed to the underlying writer
+// if it is full or the bytes are not written as part of [Encoder.Encode].
+func (e *Encoder) write(b []byte) error {
+	if e.counting {
+		e.count += len(b)
+		return nil
+	}
+
+	e.buf = append(e.buf, b...)
+	if e.w != nil && (e.encoding == 0 || len(e.buf) >= encoderBufferSize) {
+		return e.flush()
+	}
+
+	return nil
+}
+
+// WriteU8 writes a u8.
+func (e *Encoder) WriteU8(v uint8) error {
+	e.scratch[0] = v
+	return e.write(e.scratch[:1])
+}
+
+// WriteU16 writes a u16 in little endian.
+func (e *Encoder) WriteU16(v uint16) error {
+	binary.LittleEndian.PutUint16(e.scratch[:2], v)
+	return e.write(e.scratch[:2])
+}
+
+// WriteU32 writes a u32 in little endian.
+func (e *Encoder) WriteU32(v uint32) error {
+	binary.LittleEndian.PutUint32(e.scratch[:4], v)
+	return e.write(e.scratch[:4])
+}
+
+// WriteU64 writes a u64 in little endian.
+func (e *Encoder) WriteU64(v uint64) error {
+	binary.LittleEndian.PutUint64(e.scratch[:8], v)
+	return e.write(e.scratch[:8])
+}
+
+// WriteU128 writes a u128 in little endian.
+func (e *Encoder) WriteU128(v Uint128) error {
+	binary.LittleEndian.PutUint64(e.scratch[:8], v.lo)
+	binary.LittleEndian.PutUint64(e.scratch[8:16], v.hi)
+	return e.write(e.scratch[:16])
+}
+
+// WriteU256 writes a u256 in little endian. v must be non-negative and less than 2^256.
+func (e *Encoder) WriteU256(v *big.Int) error {
+	if v.Sign() < 0 || v.BitLen() > 256 {
+		return fmt.Errorf("%s is out of range for u256", v.String())
+	}
+
+	b := e.scratch[:32]
+	v.FillBytes(b)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+
+	return e.write(b)
+}
+
+// WriteBool writes a bool as a single byte of 0 or 1.
+func (e *Encoder) WriteBool(v bool) error {
+	if v {
+		return e.WriteU8(1)
+	}
+	return e.WriteU8(0)
+}
+
+// WriteULEB128 writes v as an ULEB128 encoded integer, which is how bcs encodes
+// the lengths of vectors and the indices of enum variants.
+func (e *Encoder) WriteULEB128(v uint32) error {
+	n := binary.PutUvarint(e.scratch[:MaxUleb128Length], uint64(v))
+	return e.write(e.scratch[:n])
+}
+
+// WriteLength writes the length of a vector as an ULEB128, and errors if the length is larger than [MaxUleb128].
+// The elements of the vector should be written right after.
+func (e *Encoder) WriteLength(length int) error {
+	if uint64(length) > MaxUleb128 {
+		return fmt.Errorf("length %d was larger than the max allowed ULEB128", length)
+	}
+
+	return e.WriteULEB128(uint32(length))
+}
+
+// WriteFixedBytes writes the bytes as is, without prefixing the length.
+// This is how fixed length arrays such as addresses are encoded.
+func (e *Encoder) WriteFixedBytes(b []byte) error {
+	return e.write(b)
+}
+
+// WriteBytes writes the bytes as a vector<u8>: the length in ULEB128 followed by the bytes.
+func (e *Encoder) WriteBytes(b []byte) error {
+	if err := e.WriteLength(len(b)); err != nil {
+		return err
+	}
+
+	return e.write(b)
+}
+
+// WriteString writes the string as a vector<u8>.
+func (e *Encoder) WriteString(s string) error {
+	return e.WriteBytes([]byte(s))
+}
+
+// WriteOptionTag writes the tag of an option: 1 if the value is present, 0 otherwise.
+// If isSome is true, the value should be written right after.
+func (e *Encoder) WriteOptionTag(isSome bool) error {
+	return e.WriteBool(isSome)
+}
+
+// WriteVariant writes the index of an enum variant. The content of the variant should be written right after.
+func (e *Encoder) WriteVariant(index uint32) error {
+	return e.WriteULEB128(index)
+}
@@ -158,3 +158,47 @@ func TestNestedCustom_Unmarshal(t *testing.T) {
 		}
 	}
 }
+
+// enumWithUnmarshaler is an [bcs.Enum] with its own UnmarshalBCSFrom, which takes over the decoding of the enum.
+type enumWithUnmarshaler struct {
+	A *uint8
+	B *uint16
+}
+
+func (enumWithUnmarshaler) IsBcsEnum() {}
+
+func (e *enumWithUnmarshaler) UnmarshalBCSFrom(d *bcs.Decoder) error {
+	v, err := d.ReadU8()
+	if err != nil {
+		return err
+	}
+	e.A = &v
+
+	return nil
+}
+
+func TestEnum_customUnmarshaler(t *testing.T) {
+	var e enumWithUnmarshaler
+	if _, err := bcs.Unmarshal([]byte{7}, &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.A == nil || *e.A != 7 || e.B != nil {
+		t.Fatalf("want A of 7 from the customized unmarshaler, got %v %v", e.A, e.B)
+	}
+
+	var s struct {
+		E *enumWithUnmarshaler
+		N uint8
+	}
+	if err := bcs.UnmarshalAll([]byte{7, 8}, &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.E == nil || s.E.A == nil || *s.E.A != 7 || s.N != 8 {
+		t.Fatalf("want A of 7 from the customized unmarshaler and N of 8, got %+v", s)
+	}
+
+	d := bcs.NewBytesDecoder([]byte{7, 8})
+	if err := bcs.Skip[*enumWithUnmarshaler](d); err != nil || d.Offset() != 1 {
+		t.Fatalf("want to skip 1 byte with the customized unmarshaler, got offset %d, %v", d.Offset(), err)
+	}
+}
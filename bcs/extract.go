@@ -0,0 +1,253 @@
+package bcs
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Span is the range of bytes [Start, End) a value occupies in the encoded data.
+type Span struct {
+	Start int
+	End   int
+}
+
+// Extract decodes the value at path from data, which is the encoding of a value of type typ,
+// and returns the value and the bytes it occupies in data.
+//
+// typ is either a [reflect.Type] or a sample value of the type. The path consists of field names
+// separated by ".", and indices into vectors or arrays in "[]", for example "Payload.Args[2]".
+// An empty path refers to the whole value. For an [Enum], the field name selects the variant,
+// and it is an error if the encoded value is a different variant. For an optional field,
+// it is an error if the value is not present.
+//
+// The values before the one at path are skipped without being decoded, see [Skip].
+// The fields tagged nocopy in the returned value alias data, the same as [Unmarshal].
+func Extract(data []byte, typ any, path string) (any, Span, error) {
+	d := NewBytesDecoder(data)
+	t, err := d.locate(typeOf(typ), path)
+	if err != nil {
+		return nil, Span{}, err
+	}
+
+	start := d.Offset()
+	v := reflect.New(t)
+	if err := d.decode(v.Elem()); err != nil {
+		return nil, Span{}, err
+	}
+
+	return v.Elem().Interface(), Span{Start: start, End: d.Offset()}, nil
+}
+
+// typeOf returns typ if it is a [reflect.Type], or the type of typ otherwise.
+func typeOf(typ any) reflect.Type {
+	if t, ok := typ.(reflect.Type); ok {
+		return t
+	}
+
+	return reflect.TypeOf(typ)
+}
+
+// pathSegment is either a field name or an index.
+type pathSegment struct {
+	field string
+	index int
+}
+
+func (s pathSegment) String() string {
+	if s.field != "" {
+		return "." + s.field
+	}
+
+	return "[" + strconv.Itoa(s.index) + "]"
+}
+
+// parsePath splits a path like "Payload.Args[2]" into segments.
+func parsePath(path string) ([]pathSegment, error) {
+	var r []pathSegment
+	rest := path
+	for rest != "" {
+		switch {
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("missing ] in path %s", path)
+			}
+			idx, err := strconv.Atoi(rest[1:end])
+			if err != nil || idx < 0 {
+				return nil, fmt.Errorf("invalid index %s in path %s", rest[1:end], path)
+			}
+			r = append(r, pathSegment{index: idx})
+			rest = rest[end+1:]
+		case rest[0] == '.' && len(r) > 0:
+			rest = rest[1:]
+			fallthrough
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty field name in path %s", path)
+			}
+			r = append(r, pathSegment{field: rest[:end]})
+			rest = rest[end:]
+		}
+	}
+
+	return r, nil
+}
+
+// locate skips the input until the value at path, and returns the type of that value.
+func (d *Decoder) locate(t reflect.Type, path string) (reflect.Type, error) {
+	segs, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := planFor(t)
+	for i, seg := range segs {
+		p, err = d.locateSegment(p, seg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", formatPath(segs[:i+1]), err)
+		}
+	}
+
+	return p.typ, nil
+}
+
+func formatPath(segs []pathSegment) string {
+	var b strings.Builder
+	for _, seg := range segs {
+		b.WriteString(seg.String())
+	}
+
+	return strings.TrimPrefix(b.String(), ".")
+}
+
+// locateSegment skips the input until the value at seg inside a value of plan p.
+func (d *Decoder) locateSegment(p *typePlan, seg pathSegment) (*typePlan, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	for p.kind == reflect.Pointer && !p.decodesAsEnum() && !p.hasCustomUnmarshaler() {
+		p = p.elem
+	}
+	if p.hasCustomUnmarshaler() {
+		return nil, fmt.Errorf("cannot locate into %s, which has customized unmarshaler", p.typ.String())
+	}
+
+	if seg.field != "" {
+		if p.isEnum {
+			return d.locateVariant(p.enumPlan(), seg.field)
+		}
+		if p.kind != reflect.Struct {
+			return nil, fmt.Errorf("cannot find field %s in %s", seg.field, p.typ.String())
+		}
+		return d.locateField(p, seg.field)
+	}
+
+	switch p.kind {
+	case reflect.Slice:
+		size, err := d.ReadLength()
+		if err != nil {
+			return nil, err
+		}
+		if seg.index >= size {
+			return nil, fmt.Errorf("index %d is out of range of length %d", seg.index, size)
+		}
+	case reflect.Array:
+		if seg.index >= p.typ.Len() {
+			return nil, fmt.Errorf("index %d is out of range of length %d", seg.index, p.typ.Len())
+		}
+	default:
+		return nil, fmt.Errorf("cannot index into %s", p.typ.String())
+	}
+
+	if p.elem.kind == reflect.Uint8 {
+		return p.elem, d.discard(seg.index)
+	}
+	for i := 0; i < seg.index; i++ {
+		if err := d.skipValue(p.elem); err != nil {
+			return nil, err
+		}
+	}
+
+	return p.elem, nil
+}
+
+func (d *Decoder) locateField(p *typePlan, name string) (*typePlan, error) {
+	for _, f := range p.fields {
+		if f.isVersioned() && !f.inVersion(d.version) {
+			if f.name == name {
+				return nil, fmt.Errorf("field %s is not in version %d", name, d.version)
+			}
+			continue
+		}
+		if f.tag.isOptional() {
+			isSome, err := d.ReadOptionTag()
+			if err != nil {
+				return nil, err
+			}
+			if f.name == name && !isSome {
+				return nil, fmt.Errorf("optional field %s is not present", name)
+			}
+			if !isSome {
+				continue
+			}
+			elem, err := f.optionalElem()
+			if err != nil {
+				return nil, err
+			}
+			if f.name == name {
+				return elem, nil
+			}
+			if err := d.skipValue(elem); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if f.name == name {
+			return f.plan, nil
+		}
+		if err := d.skipValue(f.plan); err != nil {
+			return nil, err
+		}
+	}
+
+	if field, ok := p.typ.FieldByName(name); ok && len(field.Index) == 1 && field.IsExported() {
+		return nil, fmt.Errorf("field %s is ignored", name)
+	}
+
+	return nil, fmt.Errorf("cannot find field %s in %s", name, p.typ.String())
+}
+
+func (d *Decoder) locateVariant(p *typePlan, name string) (*typePlan, error) {
+	if p.kind != reflect.Struct {
+		return nil, fmt.Errorf("only support struct for Enum, got %s", p.kind.String())
+	}
+	var target *fieldPlan
+	for i := range p.fields {
+		if p.fields[i].name == name {
+			target = &p.fields[i]
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("cannot find variant %s in %s", name, p.typ.String())
+	}
+
+	enumId, err := d.ReadVariant()
+	if err != nil {
+		return nil, err
+	}
+	if int(enumId) != target.index {
+		if int(enumId) < len(p.variants) && p.variants[enumId] != nil {
+			return nil, fmt.Errorf("variant %s is not set, got variant %s", name, p.variants[enumId].name)
+		}
+		return nil, fmt.Errorf("variant %s is not set, got variant %d", name, enumId)
+	}
+
+	return target.plan, nil
+}
@@ -0,0 +1,99 @@
+package bcs_test
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+func TestExtract(t *testing.T) {
+	env := &RawEnvelope{
+		Sender:  [4]byte{1, 2, 3, 4},
+		Payload: rawPayload,
+		Nonce:   11,
+	}
+	data := bcs.MustMarshal(env)
+
+	cases := []struct {
+		path     string
+		expected any
+	}{
+		{path: "", expected: env},
+		{path: "Sender[2]", expected: uint8(3)},
+		{path: "Payload.Function", expected: "transfer"},
+		{path: "Payload.Args[1]", expected: []byte{3}},
+		{path: "Payload.Enum.V0.V4.S", expected: "abc"},
+		{path: "Payload.Extra.S", expected: "x"},
+		{path: "Payload.Extra", expected: AnotherStruct{S: "x"}},
+		{path: "Payload.Amount", expected: rawPayload.Amount},
+		{path: "Nonce", expected: uint32(11)},
+	}
+
+	for _, c := range cases {
+		v, span, err := bcs.Extract(data, env, c.path)
+		if err != nil {
+			t.Fatalf("%s: %v", c.path, err)
+		}
+		if !reflect.DeepEqual(v, c.expected) {
+			t.Fatalf("%s: want %v, got %v", c.path, c.expected, v)
+		}
+		if !slices.Equal(data[span.Start:span.End], bcs.MustMarshal(c.expected)) {
+			t.Fatalf("%s: span %v doesn't match the encoding of the value", c.path, span)
+		}
+	}
+
+	v, span, err := bcs.Extract(data, reflect.TypeFor[RawEnvelope](), "Nonce")
+	if err != nil || v != uint32(11) || span.End != len(data) {
+		t.Fatalf("unexpected %v %v %v", v, span, err)
+	}
+}
+
+func TestExtract_errors(t *testing.T) {
+	data := bcs.MustMarshal(&rawPayload)
+
+	cases := []struct {
+		path string
+		msg  string
+	}{
+		{path: "Args[2]", msg: "out of range"},
+		{path: "Enum.V1", msg: "variant V1 is not set, got variant V0"},
+		{path: "Missing", msg: "cannot find field"},
+		{path: "Function[0]", msg: "cannot index"},
+		{path: "Amount.lo", msg: "customized unmarshaler"},
+		{path: "Args[x]", msg: "invalid index"},
+		{path: "Args..", msg: "empty field name"},
+	}
+
+	for _, c := range cases {
+		_, _, err := bcs.Extract(data, rawPayload, c.path)
+		if err == nil || !strings.Contains(err.Error(), c.msg) {
+			t.Fatalf("%s: want error containing %q, got %v", c.path, c.msg, err)
+		}
+	}
+
+	noExtra := rawPayload
+	noExtra.Extra = nil
+	if _, _, err := bcs.Extract(bcs.MustMarshal(&noExtra), noExtra, "Extra.S"); err == nil || !strings.Contains(err.Error(), "not present") {
+		t.Fatalf("want error for absent optional field, got %v", err)
+	}
+}
+
+func TestExtract_optionalNonPointer(t *testing.T) {
+	type optionalInterface struct {
+		X any `bcs:"optional"`
+		Y uint8
+	}
+	data := []byte{1, 5, 6}
+
+	for _, path := range []string{"X", "Y"} {
+		if _, _, err := bcs.Extract(data, optionalInterface{}, path); err == nil || !strings.Contains(err.Error(), "optional field can only be pointer") {
+			t.Fatalf("%s: want error for optional interface, got %v", path, err)
+		}
+		if _, err := bcs.Patch(data, optionalInterface{}, path, uint8(1)); err == nil {
+			t.Fatalf("%s: want error for optional interface", path)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package bcs
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// FrameLength is how the length of a frame is written by [FrameWriter] and read by [FrameReader].
+type FrameLength int
+
+const (
+	// FrameLengthULEB128 writes the length as an ULEB128, the same as the length of a vector.
+	FrameLengthULEB128 FrameLength = iota
+	// FrameLengthU32 writes the length as a u32 in little endian, so the header has a fixed size.
+	FrameLengthU32
+)
+
+// DefaultMaxFrameSize is the default max size of the payload of a frame.
+const DefaultMaxFrameSize = 16 << 20
+
+// ErrCorruptFrame is returned by [FrameReader] if the length of a frame is invalid or larger than the max frame size,
+// or the checksum doesn't match the frame.
+var ErrCorruptFrame = errors.New("corrupt frame")
+
+// crc32c is the table of CRC32C, which is used for the checksums of frames.
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// FrameWriter writes BCS values as frames, so values of different types can be put in one stream
+// and read back one by one with [FrameReader].
+//
+// Each frame is the length of the payload, the payload, and optionally the CRC32C checksum of the length and the payload
+// as a u32 in little endian. The checksum covers the length, so runs of zero bytes, which are common in the encodings
+// of small integers, are not taken as empty frames when resynchronizing. The writer and the reader must be configured
+// with the same length format and checksum option.
+type FrameWriter struct {
+	w            io.Writer
+	length       FrameLength
+	checksum     bool
+	maxFrameSize int
+	payload      []byte
+	frame        []byte
+}
+
+// NewFrameWriter creates a new [FrameWriter] writing to w. By default, the length is an ULEB128, there is no checksum,
+// and the max frame size is [DefaultMaxFrameSize].
+func NewFrameWriter(w io.Writer) *FrameWriter {
+	return &FrameWriter{
+		w:            w,
+		maxFrameSize: DefaultMaxFrameSize,
+	}
+}
+
+// SetLength sets how the length of the frames is written.
+func (w *FrameWriter) SetLength(length FrameLength) {
+	w.length = length
+}
+
+// SetChecksum sets if the CRC32C checksum of the length and the payload is written after the payload.
+func (w *FrameWriter) SetChecksum(checksum bool) {
+	w.checksum = checksum
+}
+
+// SetMaxFrameSize sets the max size of the payload, values encoded into more bytes cannot be written.
+func (w *FrameWriter) SetMaxFrameSize(maxFrameSize int) {
+	w.maxFrameSize = maxFrameSize
+}
+
+// WriteFrame encodes v and writes it as one frame.
+func (w *FrameWriter) WriteFrame(v any) error {
+	e := newBytesEncoder(w.payload[:0])
+	if err := e.Encode(v); err != nil {
+		return err
+	}
+	w.payload = e.buf
+
+	return w.WritePayload(w.payload)
+}
+
+// WritePayload writes the already encoded bytes as one frame.
+func (w *FrameWriter) WritePayload(payload []byte) error {
+	if len(payload) > w.maxFrameSize {
+		return fmt.Errorf("frame size %d is larger than the max frame size %d", len(payload), w.maxFrameSize)
+	}
+
+	// the frame is assembled in memory, so it is written to w in one call.
+	e := newBytesEncoder(w.frame[:0])
+	switch w.length {
+	case FrameLengthULEB128:
+		if err := e.WriteLength(len(payload)); err != nil {
+			return err
+		}
+	case FrameLengthU32:
+		if err := e.WriteU32(uint32(len(payload))); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown frame length format: %d", w.length)
+	}
+	if err := e.WriteFixedBytes(payload); err != nil {
+		return err
+	}
+	if w.checksum {
+		if err := e.WriteU32(crc32.Checksum(e.buf, crc32c)); err != nil {
+			return err
+		}
+	}
+	w.frame = e.buf
+
+	_, err := w.w.Write(w.frame)
+	return err
+}
+
+// FrameReader reads the frames written by [FrameWriter].
+//
+// If a frame is corrupt, [ErrCorruptFrame] is returned, and the next read resynchronizes by skipping
+// the input byte by byte until a valid frame is found. Resynchronization is only reliable with checksums,
+// otherwise any bytes with a valid length are taken as a frame.
+type FrameReader struct {
+	r            io.Reader
+	length       FrameLength
+	checksum     bool
+	maxFrameSize int
+	// buf[start:] is the input read from r but not consumed yet.
+	buf   []byte
+	start int
+	eof   bool
+	// resync indicates a corrupt frame was found, and the input is skipped until the next valid frame.
+	resync bool
+	// resynced indicates the last frame was found by resynchronization, which may be a false match inside
+	// the corrupt frame, so an incomplete frame after it is skipped as well.
+	resynced bool
+	skipped  int
+}
+
+// NewFrameReader creates a new [FrameReader] reading from r, with the same defaults as [NewFrameWriter].
+func NewFrameReader(r io.Reader) *FrameReader {
+	return &FrameReader{
+		r:            r,
+		maxFrameSize: DefaultMaxFrameSize,
+	}
+}
+
+// SetLength sets how the length of the frames is read.
+func (r *FrameReader) SetLength(length FrameLength) {
+	r.length = length
+}
+
+// SetChecksum sets if the frames have the CRC32C checksum of the length and the payload after the payload.
+func (r *FrameReader) SetChecksum(checksum bool) {
+	r.checksum = checksum
+}
+
+// SetMaxFrameSize sets the max size of the payload, frames with longer length are corrupt.
+func (r *FrameReader) SetMaxFrameSize(maxFrameSize int) {
+	r.maxFrameSize = maxFrameSize
+}
+
+// Skipped returns the number of bytes skipped to resynchronize after corrupt frames so far.
+func (r *FrameReader) Skipped() int {
+	return r.skipped
+}
+
+// ReadFrame reads the next frame and decodes it into v, which must be a pointer. The payload must be
+// decoded completely, like [UnmarshalAll].
+func (r *FrameReader) ReadFrame(v any) error {
+	payload, err := r.ReadPayload()
+	if err != nil {
+		return err
+	}
+
+	return UnmarshalAll(payload, v)
+}
+
+// ReadPayload reads the next frame and returns its payload, which is only valid until the next read.
+// [io.EOF] is returned if the input ends at the boundary of frames.
+func (r *FrameReader) ReadPayload() ([]byte, error) {
+	if r.length != FrameLengthULEB128 && r.length != FrameLengthU32 {
+		return nil, fmt.Errorf("unknown frame length format: %d", r.length)
+	}
+
+	for {
+		payload, n, err := r.parse()
+		switch {
+		case err == nil:
+			r.start += n
+			r.resynced = r.resync
+			r.resync = false
+			return payload, nil
+		case errors.Is(err, ErrCorruptFrame):
+			r.start++
+			r.skipped++
+			if !r.resync {
+				r.resync = true
+				return nil, err
+			}
+		case !r.eof:
+			if err := r.fill(); err != nil {
+				return nil, err
+			}
+		case r.start == len(r.buf):
+			return nil, io.EOF
+		case r.resync || r.resynced:
+			// the rest of the input may be the tail of a corrupt frame.
+			r.resync = true
+			r.start++
+			r.skipped++
+		default:
+			return nil, io.ErrUnexpectedEOF
+		}
+	}
+}
+
+// parse parses the frame at the start of the buffered input, and returns the payload and the size of the frame.
+// Errors other than [ErrCorruptFrame] mean the buffered input is not enough.
+func (r *FrameReader) parse() ([]byte, int, error) {
+	d := NewBytesDecoder(r.buf[r.start:])
+
+	var size int
+	switch r.length {
+	case FrameLengthULEB128:
+		n, err := d.ReadLength()
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, 0, fmt.Errorf("%w: %w", ErrCorruptFrame, err)
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		size = n
+	case FrameLengthU32:
+		n, err := d.ReadU32()
+		if err != nil {
+			return nil, 0, err
+		}
+		size = int(n)
+	}
+
+	if size > r.maxFrameSize {
+		return nil, 0, fmt.Errorf("%w: frame size %d is larger than the max frame size %d", ErrCorruptFrame, size, r.maxFrameSize)
+	}
+
+	payload, err := d.next(size)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if r.checksum {
+		frame := r.buf[r.start : r.start+d.Offset()]
+		sum, err := d.ReadU32()
+		if err != nil {
+			return nil, 0, err
+		}
+		if sum != crc32.Checksum(frame, crc32c) {
+			return nil, 0, fmt.Errorf("%w: checksum mismatch", ErrCorruptFrame)
+		}
+	}
+
+	return payload, d.Offset(), nil
+}
+
+// fill reads more input into the buffer.
+func (r *FrameReader) fill() error {
+	if r.start > 0 {
+		r.buf = r.buf[:copy(r.buf, r.buf[r.start:])]
+		r.start = 0
+	}
+	if len(r.buf) == cap(r.buf) {
+		r.buf = append(r.buf, make([]byte, max(4096, len(r.buf)))...)[:len(r.buf)]
+	}
+
+	n, err := r.r.Read(r.buf[len(r.buf):cap(r.buf)])
+	r.buf = r.buf[:len(r.buf)+n]
+	if err == io.EOF {
+		r.eof = true
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,188 @@
+package bcs_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+func TestFrame(t *testing.T) {
+	for _, length := range []bcs.FrameLength{bcs.FrameLengthULEB128, bcs.FrameLengthU32} {
+		for _, checksum := range []bool{false, true} {
+			var buf bytes.Buffer
+			w := bcs.NewFrameWriter(&buf)
+			w.SetLength(length)
+			w.SetChecksum(checksum)
+			if err := w.WriteFrame(Tree{Value: 1, Siblings: []Tree{{Value: 2}}}); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.WriteFrame("hello"); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.WriteFrame(bytes.Repeat([]byte{3}, 10000)); err != nil {
+				t.Fatal(err)
+			}
+
+			r := bcs.NewFrameReader(&buf)
+			r.SetLength(length)
+			r.SetChecksum(checksum)
+			var tree Tree
+			if err := r.ReadFrame(&tree); err != nil {
+				t.Fatal(err)
+			}
+			if tree.Value != 1 || len(tree.Siblings) != 1 || tree.Siblings[0].Value != 2 {
+				t.Fatalf("unexpected tree: %v", tree)
+			}
+			var s string
+			if err := r.ReadFrame(&s); err != nil || s != "hello" {
+				t.Fatalf("want hello, got %q, %v", s, err)
+			}
+			var b []byte
+			if err := r.ReadFrame(&b); err != nil || len(b) != 10000 {
+				t.Fatalf("want 10000 bytes, got %d, %v", len(b), err)
+			}
+			if _, err := r.ReadPayload(); err != io.EOF {
+				t.Fatalf("want io.EOF, got %v", err)
+			}
+		}
+	}
+}
+
+func TestFrame_maxFrameSize(t *testing.T) {
+	var buf bytes.Buffer
+	w := bcs.NewFrameWriter(&buf)
+	w.SetMaxFrameSize(4)
+	if err := w.WriteFrame("hello"); err == nil {
+		t.Fatal("want error for frame larger than max frame size")
+	}
+
+	w.SetMaxFrameSize(bcs.DefaultMaxFrameSize)
+	if err := w.WriteFrame("hello"); err != nil {
+		t.Fatal(err)
+	}
+	r := bcs.NewFrameReader(&buf)
+	r.SetMaxFrameSize(4)
+	if _, err := r.ReadPayload(); !errors.Is(err, bcs.ErrCorruptFrame) {
+		t.Fatalf("want corrupt frame, got %v", err)
+	}
+}
+
+func TestFrame_resync(t *testing.T) {
+	var buf bytes.Buffer
+	w := bcs.NewFrameWriter(&buf)
+	w.SetChecksum(true)
+	for _, s := range []string{"first", "second", "third"} {
+		if err := w.WriteFrame(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b := buf.Bytes()
+	// corrupt the payload of the second frame.
+	b[1+1+5+4+3] ^= 0xff
+
+	r := bcs.NewFrameReader(bytes.NewReader(b))
+	r.SetChecksum(true)
+	var got []string
+	for {
+		var s string
+		err := r.ReadFrame(&s)
+		if err == io.EOF {
+			break
+		}
+		if errors.Is(err, bcs.ErrCorruptFrame) {
+			got = append(got, "corrupt")
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, s)
+	}
+
+	want := []string{"first", "corrupt", "third"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+	if r.Skipped() != 1+1+6+4 {
+		t.Fatalf("want the second frame skipped, got %d bytes skipped", r.Skipped())
+	}
+}
+
+func TestFrame_truncated(t *testing.T) {
+	var buf bytes.Buffer
+	if err := bcs.NewFrameWriter(&buf).WriteFrame("hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	r := bcs.NewFrameReader(bytes.NewReader(buf.Bytes()[:4]))
+	if _, err := r.ReadPayload(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("want io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+func TestFrame_resyncIntegers(t *testing.T) {
+	for _, length := range []bcs.FrameLength{bcs.FrameLengthULEB128, bcs.FrameLengthU32} {
+		var buf bytes.Buffer
+		w := bcs.NewFrameWriter(&buf)
+		w.SetLength(length)
+		w.SetChecksum(true)
+		for _, v := range []uint64{100, 200, 300} {
+			if err := w.WriteFrame(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		b := buf.Bytes()
+		// corrupt the first frame, whose payload is followed by zero bytes, which must not be taken as empty frames.
+		b[len(b)/3-1] ^= 0xff
+
+		r := bcs.NewFrameReader(bytes.NewReader(b))
+		r.SetLength(length)
+		r.SetChecksum(true)
+		var got []uint64
+		corrupt := 0
+		for {
+			var v uint64
+			err := r.ReadFrame(&v)
+			if err == io.EOF {
+				break
+			}
+			if errors.Is(err, bcs.ErrCorruptFrame) {
+				corrupt++
+				continue
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, v)
+		}
+
+		if corrupt != 1 || len(got) != 2 || got[0] != 200 || got[1] != 300 {
+			t.Fatalf("want 1 corrupt frame and [200 300], got %d corrupt frames and %v", corrupt, got)
+		}
+		if r.Skipped() != len(b)/3 {
+			t.Fatalf("want the first frame skipped, got %d bytes skipped", r.Skipped())
+		}
+	}
+}
+
+func TestFrame_resyncIncomplete(t *testing.T) {
+	// without checksums, the length 1 after the corrupt length is taken as a frame, and the incomplete frame
+	// of length 5 after it is skipped instead of ending the stream.
+	r := bcs.NewFrameReader(bytes.NewReader([]byte{11, 1, 0xaa, 5, 1, 2}))
+	r.SetMaxFrameSize(10)
+	if _, err := r.ReadPayload(); !errors.Is(err, bcs.ErrCorruptFrame) {
+		t.Fatalf("want corrupt frame, got %v", err)
+	}
+	for _, want := range []byte{0xaa, 2} {
+		payload, err := r.ReadPayload()
+		if err != nil || len(payload) != 1 || payload[0] != want {
+			t.Fatalf("want payload [%d], got %v, %v", want, payload, err)
+		}
+	}
+	if _, err := r.ReadPayload(); err != io.EOF {
+		t.Fatalf("want io.EOF, got %v", err)
+	}
+}
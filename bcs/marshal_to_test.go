@@ -0,0 +1,135 @@
+package bcs_test
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+var sizeCases = []any{
+	uint8(1),
+	"abc",
+	[]uint16{1, 2},
+	&rawPayload,
+	RawEnvelope{Payload: rawPayload},
+	&UnmarshalStruct{OptionalStruct: &AnotherStruct{S: "hey"}},
+}
+
+func TestSize(t *testing.T) {
+	for _, v := range sizeCases {
+		b, err := bcs.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := bcs.Size(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != len(b) {
+			t.Fatalf("%v: want size %d, got %d", v, len(b), n)
+		}
+	}
+
+	if _, err := bcs.Size(&EnumExample{}); err == nil {
+		t.Fatalf("unset enum should error")
+	}
+}
+
+func TestAppendMarshal(t *testing.T) {
+	prefix := []byte{0xff, 0xfe}
+	for _, v := range sizeCases {
+		b, err := bcs.AppendMarshal(slices.Clone(prefix), v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(b, append(slices.Clone(prefix), bcs.MustMarshal(v)...)) {
+			t.Fatalf("%v: unexpected bytes %v", v, b)
+		}
+	}
+
+	b, err := bcs.AppendMarshal(slices.Clone(prefix), &EnumExample{})
+	if err == nil || !slices.Equal(b, prefix) {
+		t.Fatalf("want the prefix and error for unset enum, got %v %v", b, err)
+	}
+}
+
+func TestMarshalTo(t *testing.T) {
+	for _, v := range sizeCases {
+		expected := bcs.MustMarshal(v)
+
+		buf := make([]byte, len(expected)+3)
+		n, err := bcs.MarshalTo(buf, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != len(expected) || !slices.Equal(buf[:n], expected) {
+			t.Fatalf("%v: want %v, got %v", v, expected, buf[:n])
+		}
+
+		if len(expected) == 0 {
+			continue
+		}
+		short := make([]byte, len(expected)-1)
+		if _, err := bcs.MarshalTo(short, v); !errors.Is(err, io.ErrShortBuffer) {
+			t.Fatalf("%v: want short buffer error, got %v", v, err)
+		}
+		if slices.ContainsFunc(short, func(b byte) bool { return b != 0 }) {
+			t.Fatalf("%v: short buffer is written %v", v, short)
+		}
+	}
+
+	buf := []byte{1, 2, 3}
+	if _, err := bcs.MarshalTo(buf, &EnumExample{}); err == nil || !slices.Equal(buf, []byte{1, 2, 3}) {
+		t.Fatalf("want buffer untouched and error for unset enum, got %v %v", buf, err)
+	}
+}
+
+// countingWriter counts the calls to Write.
+type countingWriter struct {
+	writes int
+	data   []byte
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	w.data = append(w.data, p...)
+	return len(p), nil
+}
+
+func TestEncoder_buffered(t *testing.T) {
+	w := &countingWriter{}
+	e := bcs.NewEncoder(w)
+	if err := e.Encode(&rawPayload); err != nil {
+		t.Fatal(err)
+	}
+	if w.writes != 1 {
+		t.Fatalf("want 1 write, got %d", w.writes)
+	}
+	if !slices.Equal(w.data, bcs.MustMarshal(&rawPayload)) {
+		t.Fatalf("unexpected output %v", w.data)
+	}
+
+	// primitives written outside of Encode go to the writer immediately.
+	if err := e.WriteU8(7); err != nil {
+		t.Fatal(err)
+	}
+	if w.writes != 2 || w.data[len(w.data)-1] != 7 {
+		t.Fatalf("primitive is not written through")
+	}
+
+	// large values are flushed in chunks.
+	w = &countingWriter{}
+	large := make([][]byte, 100)
+	for i := range large {
+		large[i] = make([]byte, 1000)
+	}
+	if err := bcs.NewEncoder(w).Encode(large); err != nil {
+		t.Fatal(err)
+	}
+	if w.writes < 2 || !slices.Equal(w.data, bcs.MustMarshal(large)) {
+		t.Fatalf("unexpected writes %d", w.writes)
+	}
+}
@@ -4,3 +4,12 @@ package bcs
 type Marshaler interface {
 	MarshalBCS() ([]byte, error)
 }
+
+// MarshalerTo customizes the marshalling behavior for a type by writing directly into the [Encoder].
+//
+// Compared with [Marshaler], there is no need to allocate the intermediate bytes, and the nested values
+// encoded with [Encoder.Encode] share the depth limit of the encoder. If a type implements both
+// [MarshalerTo] and [Marshaler], [MarshalerTo] is used.
+type MarshalerTo interface {
+	MarshalBCSTo(*Encoder) error
+}
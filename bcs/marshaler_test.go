@@ -0,0 +1,150 @@
+package bcs_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+// Address is a 32 byte address, encoded as hex string in golang.
+type Address string
+
+func (a Address) MarshalBCSTo(e *bcs.Encoder) error {
+	b, err := hex.DecodeString(strings.TrimPrefix(string(a), "0x"))
+	if err != nil {
+		return err
+	}
+	if len(b) != 32 {
+		return fmt.Errorf("address must be 32 bytes, got %d", len(b))
+	}
+
+	return e.WriteFixedBytes(b)
+}
+
+func (a *Address) UnmarshalBCSFrom(d *bcs.Decoder) error {
+	var b [32]byte
+	if err := d.ReadFixedBytes(b[:]); err != nil {
+		return err
+	}
+
+	*a = Address("0x" + hex.EncodeToString(b[:]))
+
+	return nil
+}
+
+type Transfer struct {
+	From   Address
+	To     []Address
+	Amount *uint64 `bcs:"optional"`
+	Memo   string
+}
+
+func TestMarshalerTo(t *testing.T) {
+	from := Address("0x" + strings.Repeat("01", 32))
+	to := Address("0x" + strings.Repeat("ab", 32))
+	amount := uint64(5)
+	v := &Transfer{
+		From:   from,
+		To:     []Address{to},
+		Amount: &amount,
+		Memo:   "m",
+	}
+
+	b, err := bcs.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := slices.Concat(
+		bytes.Repeat([]byte{1}, 32),
+		[]byte{1},
+		bytes.Repeat([]byte{0xab}, 32),
+		[]byte{1, 5, 0, 0, 0, 0, 0, 0, 0},
+		[]byte{1, 'm'},
+	)
+	if !slices.Equal(b, expected) {
+		t.Fatalf("want: %v\ngot:  %v", expected, b)
+	}
+
+	nv := new(Transfer)
+	n, err := bcs.Unmarshal(b, nv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(b) {
+		t.Fatalf("want parsed length: %d, got: %d", len(b), n)
+	}
+	if nv.From != from || len(nv.To) != 1 || nv.To[0] != to || nv.Amount == nil || *nv.Amount != 5 || nv.Memo != "m" {
+		t.Fatalf("want: %v, got: %v", v, nv)
+	}
+}
+
+type Node struct {
+	Children []Node
+}
+
+func TestMaxDepth(t *testing.T) {
+	// each level of nesting adds 1 byte of vector length.
+	deep := []byte{0}
+	for i := 0; i < bcs.DefaultMaxDepth; i++ {
+		deep = append([]byte{1}, deep...)
+	}
+
+	if _, err := bcs.Unmarshal(deep, new(Node)); err == nil || !strings.Contains(err.Error(), "max depth") {
+		t.Fatalf("expected max depth error, got %v", err)
+	}
+	if _, err := bcs.Unmarshal(deep[1:], new(Node)); err != nil {
+		t.Fatal(err)
+	}
+
+	v := Node{}
+	for i := 0; i < bcs.DefaultMaxDepth; i++ {
+		v = Node{Children: []Node{v}}
+	}
+	if _, err := bcs.Marshal(v); err == nil || !strings.Contains(err.Error(), "max depth") {
+		t.Fatalf("expected max depth error, got %v", err)
+	}
+}
+
+// Short encodes itself with a pointer receiver [bcs.Marshaler] as a single byte.
+type Short struct {
+	V uint64
+}
+
+func (s *Short) MarshalBCS() ([]byte, error) {
+	return []byte{uint8(s.V)}, nil
+}
+
+func TestMarshaler_pointerReceiver(t *testing.T) {
+	type holder struct {
+		C Compact
+		S Short
+		I any
+	}
+	v := holder{C: Compact{V: 7}, S: Short{V: 8}, I: Compact{V: 9}}
+	want := []byte{7, 8, 9}
+
+	for _, value := range []any{v, &v} {
+		b, err := bcs.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, want) {
+			t.Fatalf("%T, want: %v\ngot:  %v", value, want, b)
+		}
+	}
+
+	for _, value := range []any{Compact{V: 7}, &Compact{V: 7}} {
+		if b := bcs.MustMarshal(value); !bytes.Equal(b, []byte{7}) {
+			t.Fatalf("%T, want: [7], got: %v", value, b)
+		}
+		if n, err := bcs.Size(value); err != nil || n != 1 {
+			t.Fatalf("%T, want size 1, got %d %v", value, n, err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package bcs
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+)
+
+// Patch replaces the value at path in data, which is the encoding of a value of type typ,
+// with the encoding of newValue, and returns the patched bytes. data itself is not modified.
+//
+// typ and path follow the same rules as [Extract]. newValue must be of the same type as the value at path,
+// although pointers are allowed on either side since they are encoded as the values they point to.
+// The values other than the one at path are copied without being decoded.
+func Patch(data []byte, typ any, path string, newValue any) ([]byte, error) {
+	d := NewBytesDecoder(data)
+	t, err := d.locate(typeOf(typ), path)
+	if err != nil {
+		return nil, err
+	}
+
+	start := d.Offset()
+	if err := d.skip(t); err != nil {
+		return nil, err
+	}
+	end := d.Offset()
+
+	if err := checkPatchType(t, reflect.TypeOf(newValue)); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.Grow(len(data))
+	b.Write(data[:start])
+	if err := NewEncoder(&b).Encode(newValue); err != nil {
+		return nil, err
+	}
+	b.Write(data[end:])
+
+	return b.Bytes(), nil
+}
+
+// AppendToVector appends elems to the vector at path in data, which is the encoding of a value of type typ,
+// and returns the patched bytes. data itself is not modified.
+//
+// typ and path follow the same rules as [Extract], and the value at path must be a slice. The length prefix
+// of the vector is rewritten, and the elements already in the vector are copied without being decoded.
+func AppendToVector(data []byte, typ any, path string, elems ...any) ([]byte, error) {
+	d := NewBytesDecoder(data)
+	t, err := d.locate(typeOf(typ), path)
+	if err != nil {
+		return nil, err
+	}
+	t = derefType(t)
+	if t.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("%s is %s, not a vector", path, t.String())
+	}
+	for i, elem := range elems {
+		if err := checkPatchType(t.Elem(), reflect.TypeOf(elem)); err != nil {
+			return nil, fmt.Errorf("element %d: %w", i, err)
+		}
+	}
+
+	start := d.Offset()
+	size, err := d.ReadLength()
+	if err != nil {
+		return nil, err
+	}
+	contentStart := d.Offset()
+	for i := 0; i < size; i++ {
+		if err := d.skip(t.Elem()); err != nil {
+			return nil, err
+		}
+	}
+	end := d.Offset()
+
+	var b bytes.Buffer
+	b.Grow(len(data))
+	b.Write(data[:start])
+	e := NewEncoder(&b)
+	if err := e.WriteLength(size + len(elems)); err != nil {
+		return nil, err
+	}
+	b.Write(data[contentStart:end])
+	for _, elem := range elems {
+		if err := e.Encode(elem); err != nil {
+			return nil, err
+		}
+	}
+	b.Write(data[end:])
+
+	return b.Bytes(), nil
+}
+
+// derefType removes the pointers of t, unless t is an [Enum] or has customized unmarshaler.
+func derefType(t reflect.Type) reflect.Type {
+	p := planFor(t)
+	for p.kind == reflect.Pointer && !p.decodesAsEnum() && !p.hasCustomUnmarshaler() {
+		p = p.elem
+	}
+
+	return p.typ
+}
+
+// checkPatchType checks the value of type actual can replace a value of type expected.
+func checkPatchType(expected, actual reflect.Type) error {
+	if actual == nil {
+		return fmt.Errorf("cannot patch %s with nil", expected.String())
+	}
+
+	for expected.Kind() == reflect.Pointer {
+		expected = expected.Elem()
+	}
+	for actual.Kind() == reflect.Pointer {
+		actual = actual.Elem()
+	}
+	if expected != actual {
+		return fmt.Errorf("cannot patch %s with %s", expected.String(), actual.String())
+	}
+
+	return nil
+}
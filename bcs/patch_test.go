@@ -0,0 +1,93 @@
+package bcs_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+func TestPatch(t *testing.T) {
+	env := RawEnvelope{
+		Sender:  [4]byte{1, 2, 3, 4},
+		Payload: rawPayload,
+		Nonce:   11,
+	}
+	data := bcs.MustMarshal(&env)
+	original := slices.Clone(data)
+
+	patched, err := bcs.Patch(data, env, "Payload.Function", "swap_exact_in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(data, original) {
+		t.Fatalf("input is modified")
+	}
+
+	expected := env
+	expected.Payload.Function = "swap_exact_in"
+	if !slices.Equal(patched, bcs.MustMarshal(&expected)) {
+		t.Fatalf("want: %v\ngot:  %v", bcs.MustMarshal(&expected), patched)
+	}
+
+	patched, err = bcs.Patch(patched, env, "Nonce", uint32(12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected.Nonce = 12
+	if !slices.Equal(patched, bcs.MustMarshal(&expected)) {
+		t.Fatalf("want: %v\ngot:  %v", bcs.MustMarshal(&expected), patched)
+	}
+
+	patched, err = bcs.Patch(patched, env, "Payload.Extra", &AnotherStruct{S: "longer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected.Payload.Extra = &AnotherStruct{S: "longer"}
+	if !slices.Equal(patched, bcs.MustMarshal(&expected)) {
+		t.Fatalf("want: %v\ngot:  %v", bcs.MustMarshal(&expected), patched)
+	}
+
+	if _, err := bcs.Patch(data, env, "Nonce", uint64(12)); err == nil || !strings.Contains(err.Error(), "cannot patch") {
+		t.Fatalf("want type mismatch error, got %v", err)
+	}
+}
+
+func TestAppendToVector(t *testing.T) {
+	env := RawEnvelope{
+		Sender:  [4]byte{1, 2, 3, 4},
+		Payload: rawPayload,
+		Nonce:   11,
+	}
+	data := bcs.MustMarshal(&env)
+
+	patched, err := bcs.AppendToVector(data, env, "Payload.Args", []byte{4, 5}, []byte{6})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := env
+	expected.Payload.Args = [][]byte{{1, 2}, {3}, {4, 5}, {6}}
+	if !slices.Equal(patched, bcs.MustMarshal(&expected)) {
+		t.Fatalf("want: %v\ngot:  %v", bcs.MustMarshal(&expected), patched)
+	}
+
+	// length prefix grows from 1 byte to 2 bytes.
+	v := make([]uint16, 127)
+	data = bcs.MustMarshal(v)
+	patched, err = bcs.AppendToVector(data, v, "", uint16(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(patched, bcs.MustMarshal(append(v, 1))) {
+		t.Fatalf("unexpected encoding after append")
+	}
+
+	if _, err := bcs.AppendToVector(bcs.MustMarshal(&env), env, "Nonce", uint32(1)); err == nil || !strings.Contains(err.Error(), "not a vector") {
+		t.Fatalf("want not a vector error, got %v", err)
+	}
+	if _, err := bcs.AppendToVector(bcs.MustMarshal(&env), env, "Payload.Args", "a"); err == nil || !strings.Contains(err.Error(), "cannot patch") {
+		t.Fatalf("want type mismatch error, got %v", err)
+	}
+}
@@ -0,0 +1,330 @@
+package bcs
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var (
+	enumType            = reflect.TypeFor[Enum]()
+	marshalerType       = reflect.TypeFor[Marshaler]()
+	marshalerToType     = reflect.TypeFor[MarshalerTo]()
+	unmarshalerType     = reflect.TypeFor[Unmarshaler]()
+	unmarshalerFromType = reflect.TypeFor[UnmarshalerFrom]()
+)
+
+// typePlan is the precompiled information of a type, so the encoder and decoder don't need to
+// inspect the type with reflection or parse the tags every time a value of the type is encountered.
+type typePlan struct {
+	typ  reflect.Type
+	kind reflect.Kind
+	// err is the error found when compiling the plan, for example, an invalid tag.
+	err error
+
+	// marshalerTo and marshaler indicate the type implements [MarshalerTo] or [Marshaler].
+	// The *Addr variants indicate only the pointer to the type implements them, which are called
+	// on the value, or on a copy of it if it is not addressable.
+	marshalerTo     bool
+	marshalerToAddr bool
+	marshaler       bool
+	marshalerAddr   bool
+	// unmarshalerFrom and unmarshaler indicate the pointer to the type implements [UnmarshalerFrom] or [Unmarshaler].
+	unmarshalerFrom bool
+	unmarshaler     bool
+	isEnum          bool
+	// validator indicates the pointer to the type implements [BCSValidator].
+	// preparer and preparerAddr indicate the type or only its pointer implements [BCSPreparer].
+	// They are not set for pointers and interfaces, which are validated or prepared through their elements.
+	validator    bool
+	preparer     bool
+	preparerAddr bool
+
+	// elem is the plan for the element of pointer, slice, and array.
+	elem *typePlan
+	// fields are the exported and not ignored fields of struct.
+	fields []fieldPlan
+	// variants are the fields of an [Enum] indexed by the variant index, nil if the variant is invalid.
+	variants []*fieldPlan
+
+	// size is the encoded size of the type if it is fixed, -1 otherwise.
+	size int
+	// minSize is the minimal encoded size of the type, used to bound the preallocation of slices.
+	// It is 0 if unknown, for example, for types with customized unmarshaler.
+	minSize int
+	// plain indicates the memory of the type is the same as its encoding, see [isPlain].
+	plain bool
+}
+
+// fieldPlan is the precompiled information of a struct field.
+type fieldPlan struct {
+	index int
+	name  string
+	tag   tagValue
+	plan  *typePlan
+	// constraints are the checks on the value declared in the tag, nil if there are none.
+	constraints *constraints
+	// since and until are the first and the last schema versions the field is in, 0 if not set, see [fieldPlan.inVersion].
+	since int
+	until int
+}
+
+// optionalElem returns the plan of the value of an optional field, which must be a pointer.
+func (f *fieldPlan) optionalElem() (*typePlan, error) {
+	if f.plan.kind != reflect.Pointer {
+		return nil, fmt.Errorf("optional field can only be pointer")
+	}
+
+	return f.plan.elem, nil
+}
+
+// hasCustomMarshaler checks if the encoder will use [MarshalerTo] or [Marshaler] for the type.
+func (p *typePlan) hasCustomMarshaler() bool {
+	return p.marshalerTo || p.marshalerToAddr || p.marshaler || p.marshalerAddr
+}
+
+// hasCustomUnmarshaler checks if the decoder will use [UnmarshalerFrom] or [Unmarshaler] for the type.
+func (p *typePlan) hasCustomUnmarshaler() bool {
+	return p.unmarshalerFrom || p.unmarshaler
+}
+
+// decodesAsEnum checks if the decoder will decode the value as an [Enum]. An [Enum] behind a pointer
+// is still decoded by the customized unmarshalers of the struct, which the plan of the pointer doesn't have.
+func (p *typePlan) decodesAsEnum() bool {
+	return p.isEnum && !p.enumPlan().hasCustomUnmarshaler()
+}
+
+// enumPlan returns the plan for the struct of an [Enum], which may be behind a pointer.
+func (p *typePlan) enumPlan() *typePlan {
+	if p.kind == reflect.Pointer {
+		return p.elem
+	}
+
+	return p
+}
+
+// plans caches *typePlan by [reflect.Type].
+var plans sync.Map
+
+// planFor returns the plan of type t, compiling it if t is seen for the first time.
+func planFor(t reflect.Type) *typePlan {
+	if p, ok := plans.Load(t); ok {
+		return p.(*typePlan)
+	}
+
+	b := &planBuilder{building: make(map[reflect.Type]*typePlan)}
+	b.build(t)
+
+	// plans of recursive types reference each other, publish them all.
+	for bt, bp := range b.building {
+		plans.LoadOrStore(bt, bp)
+	}
+
+	p, _ := plans.Load(t)
+	return p.(*typePlan)
+}
+
+// planBuilder compiles the plans of a type and the types it contains.
+// Plans are registered in building before their elements and fields are compiled,
+// so recursive types refer to the plan being built instead of compiling it again.
+type planBuilder struct {
+	building map[reflect.Type]*typePlan
+}
+
+func (b *planBuilder) build(t reflect.Type) *typePlan {
+	if p, ok := plans.Load(t); ok {
+		return p.(*typePlan)
+	}
+	if p, ok := b.building[t]; ok {
+		return p
+	}
+
+	p := &typePlan{
+		typ:  t,
+		kind: t.Kind(),
+		size: -1,
+	}
+	b.building[t] = p
+
+	pt := t
+	if t.Kind() != reflect.Pointer {
+		pt = reflect.PointerTo(t)
+	}
+	if t.Kind() != reflect.Interface {
+		p.marshalerTo = t.Implements(marshalerToType)
+		p.marshalerToAddr = !p.marshalerTo && pt.Implements(marshalerToType)
+		p.marshaler = t.Implements(marshalerType)
+		p.marshalerAddr = !p.marshaler && pt.Implements(marshalerType)
+		p.unmarshalerFrom = pt.Implements(unmarshalerFromType)
+		p.unmarshaler = pt.Implements(unmarshalerType)
+		p.isEnum = t.Implements(enumType)
+	}
+	if t.Kind() != reflect.Interface && t.Kind() != reflect.Pointer {
+		p.validator = pt.Implements(validatorType)
+		p.preparer = t.Implements(preparerType)
+		p.preparerAddr = !p.preparer && pt.Implements(preparerType)
+	}
+
+	switch p.kind {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		p.elem = b.build(t.Elem())
+	case reflect.Struct:
+		b.buildFields(p)
+	}
+
+	if !p.hasCustomMarshaler() && !p.hasCustomUnmarshaler() && !p.isEnum {
+		p.size = fixedSize(p)
+	}
+	p.minSize = minSize(p)
+	p.plain = isPlain(p)
+
+	return p
+}
+
+func (b *planBuilder) buildFields(p *typePlan) {
+	t := p.typ
+	if p.isEnum {
+		p.variants = make([]*fieldPlan, t.NumField())
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag, err := parseTag(field.Tag.Get(tagName))
+		if err != nil {
+			p.err = err
+			return
+		}
+		if tag.flags.isIgnored() {
+			continue
+		}
+
+		fp := fieldPlan{
+			index:       i,
+			name:        field.Name,
+			tag:         tag.flags,
+			plan:        b.build(field.Type),
+			constraints: tag.constraints,
+			since:       tag.since,
+			until:       tag.until,
+		}
+		if fp.isVersioned() && p.isEnum {
+			p.err = fmt.Errorf("field %s: since and until are not supported for enum variants", field.Name)
+			return
+		}
+		if c := fp.constraints; c != nil {
+			if err := c.compile(fp.plan); err != nil {
+				p.err = fmt.Errorf("field %s: %w", field.Name, err)
+				return
+			}
+		}
+		p.fields = append(p.fields, fp)
+	}
+
+	if p.isEnum {
+		for i := range p.fields {
+			p.variants[p.fields[i].index] = &p.fields[i]
+		}
+	}
+}
+
+// fixedSize computes the encoded size of the type if it doesn't depend on the value.
+func fixedSize(p *typePlan) int {
+	switch p.kind {
+	case reflect.Bool, reflect.Int8, reflect.Uint8, reflect.Int16, reflect.Uint16,
+		reflect.Int32, reflect.Uint32, reflect.Int64, reflect.Uint64:
+		return int(p.typ.Size())
+	case reflect.Array:
+		if p.elem.size < 0 {
+			return -1
+		}
+		return p.elem.size * p.typ.Len()
+	case reflect.Struct:
+		if p.err != nil {
+			return -1
+		}
+		size := 0
+		for _, f := range p.fields {
+			if f.tag.isOptional() || f.isVersioned() || f.plan.size < 0 {
+				return -1
+			}
+			size += f.plan.size
+		}
+		return size
+	default:
+		return -1
+	}
+}
+
+// minSize computes the minimal encoded size of the type. The plans of recursive types
+// may still be compiling, whose minSize is 0, so the result is a lower bound.
+func minSize(p *typePlan) int {
+	if p.size >= 0 {
+		return p.size
+	}
+	if p.err != nil || p.hasCustomUnmarshaler() {
+		return 0
+	}
+	if p.isEnum {
+		// the variant index.
+		return 1
+	}
+
+	switch p.kind {
+	case reflect.Pointer:
+		return p.elem.minSize
+	case reflect.Slice, reflect.String:
+		// the length.
+		return 1
+	case reflect.Array:
+		return p.elem.minSize * p.typ.Len()
+	case reflect.Struct:
+		size := 0
+		for _, f := range p.fields {
+			if f.isVersioned() {
+				continue
+			}
+			if f.tag.isOptional() {
+				size++
+			} else {
+				size += f.plan.minSize
+			}
+		}
+		return size
+	default:
+		return 0
+	}
+}
+
+// Codec encodes and decodes values of type T.
+type Codec[T any] interface {
+	// Encode writes v into the encoder.
+	Encode(e *Encoder, v T) error
+	// Decode reads a value from the decoder.
+	Decode(d *Decoder) (T, error)
+}
+
+// CodecFor returns the [Codec] for type T following the rules of [Marshal] and [Unmarshal].
+//
+// The type is inspected once, and the plan of how to encode and decode it is cached and shared by
+// [Marshal], [Unmarshal], [Encoder] and [Decoder], so there is no need to keep the returned codec around.
+func CodecFor[T any]() Codec[T] {
+	return reflectCodec[T]{plan: planFor(reflect.TypeFor[T]())}
+}
+
+// reflectCodec is the [Codec] using the reflection based encoder and decoder.
+type reflectCodec[T any] struct {
+	plan *typePlan
+}
+
+func (c reflectCodec[T]) Encode(e *Encoder, v T) error {
+	return e.encodeTop(reflect.ValueOf(&v).Elem(), c.plan)
+}
+
+func (c reflectCodec[T]) Decode(d *Decoder) (T, error) {
+	var v T
+	err := d.decodeValue(reflect.ValueOf(&v).Elem(), c.plan)
+	return v, err
+}
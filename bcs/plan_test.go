@@ -0,0 +1,104 @@
+package bcs_test
+
+import (
+	"bytes"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+type Tree struct {
+	Value    uint16
+	Left     *Tree `bcs:"optional"`
+	Right    *Tree `bcs:"optional"`
+	Siblings []Tree
+}
+
+func TestCodecFor(t *testing.T) {
+	v := Tree{
+		Value: 1,
+		Left:  &Tree{Value: 2, Siblings: []Tree{{Value: 4}}},
+		Right: &Tree{Value: 3},
+	}
+
+	c := bcs.CodecFor[Tree]()
+
+	var buf bytes.Buffer
+	if err := c.Encode(bcs.NewEncoder(&buf), v); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := bcs.MustMarshal(&v)
+	if !slices.Equal(buf.Bytes(), expected) {
+		t.Fatalf("want: %v\ngot:  %v", expected, buf.Bytes())
+	}
+
+	d := bcs.NewDecoder(bytes.NewReader(expected))
+	nv, err := c.Decode(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Offset() != len(expected) {
+		t.Fatalf("want offset: %d, got: %d", len(expected), d.Offset())
+	}
+	if !slices.Equal(bcs.MustMarshal(&nv), expected) {
+		t.Fatalf("decoded value doesn't round trip")
+	}
+
+	// the plan is shared with other types and codecs
+	s := bcs.CodecFor[[]Tree]()
+	buf.Reset()
+	if err := s.Encode(bcs.NewEncoder(&buf), []Tree{v}); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(buf.Bytes(), append([]byte{1}, expected...)) {
+		t.Fatalf("unexpected slice encoding: %v", buf.Bytes())
+	}
+}
+
+func TestCodecFor_concurrent(t *testing.T) {
+	type Concurrent struct {
+		A []Tree
+		B map[string]int `bcs:"-"`
+		C *EnumExample
+	}
+
+	v := Concurrent{A: []Tree{{Value: 9}}, C: &EnumExample{V2: new(uint32)}}
+	expected := []byte{1, 9, 0, 0, 0, 0, 2, 0, 0, 0, 0}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b, err := bcs.Marshal(v)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if !slices.Equal(b, expected) {
+				t.Errorf("want: %v\ngot:  %v", expected, b)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestMarshal_nilPointer(t *testing.T) {
+	type WithPointer struct {
+		A *uint16
+		B *MyStruct
+	}
+
+	b, err := bcs.Marshal(WithPointer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{0, 0, 0, 0, 0}
+	if !slices.Equal(b, expected) {
+		t.Fatalf("want: %v\ngot:  %v", expected, b)
+	}
+}
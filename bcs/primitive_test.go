@@ -0,0 +1,156 @@
+package bcs_test
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+func TestEncoder_primitives(t *testing.T) {
+	var buf bytes.Buffer
+	e := bcs.NewEncoder(&buf)
+
+	u256, _ := new(big.Int).SetString("0102030405060708091011121314151617181920212223242526272829303132", 16)
+
+	steps := []error{
+		e.WriteU8(1),
+		e.WriteU16(4660),
+		e.WriteU32(305419896),
+		e.WriteU64(1311768467750121216),
+		e.WriteU128(*bcs.NewUint128FromUint64(1, 2)),
+		e.WriteU256(u256),
+		e.WriteBool(true),
+		e.WriteULEB128(9487),
+		e.WriteBytes([]byte{0xC0, 0xDE}),
+		e.WriteFixedBytes([]byte{0xC0, 0xDE}),
+		e.WriteString("a"),
+		e.WriteOptionTag(false),
+		e.WriteVariant(4),
+	}
+	for i, err := range steps {
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+	}
+
+	expected := []byte{
+		1,
+		0x34, 0x12,
+		0x78, 0x56, 0x34, 0x12,
+		0x00, 0xef, 0xcd, 0xab, 0x78, 0x56, 0x34, 0x12,
+		1, 0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0,
+		0x32, 0x31, 0x30, 0x29, 0x28, 0x27, 0x26, 0x25, 0x24, 0x23, 0x22, 0x21, 0x20, 0x19, 0x18, 0x17,
+		0x16, 0x15, 0x14, 0x13, 0x12, 0x11, 0x10, 0x09, 0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01,
+		1,
+		0x8f, 0x4a,
+		2, 0xC0, 0xDE,
+		0xC0, 0xDE,
+		1, 97,
+		0,
+		4,
+	}
+	if !slices.Equal(buf.Bytes(), expected) {
+		t.Fatalf("want: %v\ngot:  %v", expected, buf.Bytes())
+	}
+
+	d := bcs.NewDecoder(bytes.NewReader(expected))
+	if v, err := d.ReadU8(); err != nil || v != 1 {
+		t.Fatalf("u8: %v %v", v, err)
+	}
+	if v, err := d.ReadU16(); err != nil || v != 4660 {
+		t.Fatalf("u16: %v %v", v, err)
+	}
+	if v, err := d.ReadU32(); err != nil || v != 305419896 {
+		t.Fatalf("u32: %v %v", v, err)
+	}
+	if v, err := d.ReadU64(); err != nil || v != 1311768467750121216 {
+		t.Fatalf("u64: %v %v", v, err)
+	}
+	if v, err := d.ReadU128(); err != nil || v.Cmp(bcs.NewUint128FromUint64(1, 2)) != 0 {
+		t.Fatalf("u128: %v %v", v, err)
+	}
+	if v, err := d.ReadU256(); err != nil || v.Cmp(u256) != 0 {
+		t.Fatalf("u256: %v %v", v, err)
+	}
+	if v, err := d.ReadBool(); err != nil || !v {
+		t.Fatalf("bool: %v %v", v, err)
+	}
+	if v, err := d.ReadULEB128(); err != nil || v != 9487 {
+		t.Fatalf("uleb128: %v %v", v, err)
+	}
+	if v, err := d.ReadBytes(); err != nil || !slices.Equal(v, []byte{0xC0, 0xDE}) {
+		t.Fatalf("bytes: %v %v", v, err)
+	}
+	fixed := make([]byte, 2)
+	if err := d.ReadFixedBytes(fixed); err != nil || !slices.Equal(fixed, []byte{0xC0, 0xDE}) {
+		t.Fatalf("fixed bytes: %v %v", fixed, err)
+	}
+	if v, err := d.ReadString(); err != nil || v != "a" {
+		t.Fatalf("string: %v %v", v, err)
+	}
+	if v, err := d.ReadOptionTag(); err != nil || v {
+		t.Fatalf("option tag: %v %v", v, err)
+	}
+	if v, err := d.ReadVariant(); err != nil || v != 4 {
+		t.Fatalf("variant: %v %v", v, err)
+	}
+	if d.Offset() != len(expected) {
+		t.Fatalf("want offset: %d, got: %d", len(expected), d.Offset())
+	}
+	if _, err := d.ReadU8(); err == nil {
+		t.Fatalf("expect error at the end of input")
+	}
+}
+
+func TestEncoder_WriteU256OutOfRange(t *testing.T) {
+	e := bcs.NewEncoder(&bytes.Buffer{})
+	if err := e.WriteU256(big.NewInt(-1)); err == nil {
+		t.Fatalf("negative should error")
+	}
+	if err := e.WriteU256(new(big.Int).Lsh(big.NewInt(1), 256)); err == nil {
+		t.Fatalf("2^256 should error")
+	}
+}
+
+func TestDecoder_invalidTags(t *testing.T) {
+	if _, err := bcs.NewDecoder(bytes.NewReader([]byte{2})).ReadBool(); err == nil {
+		t.Fatalf("bool of 2 should error")
+	}
+	if _, err := bcs.NewDecoder(bytes.NewReader([]byte{2})).ReadOptionTag(); err == nil {
+		t.Fatalf("option tag of 2 should error")
+	}
+	var b bool
+	if _, err := bcs.Unmarshal([]byte{2}, &b); err == nil {
+		t.Fatalf("bool of 2 should error")
+	}
+	var o struct {
+		V *uint8 `bcs:"optional"`
+	}
+	if _, err := bcs.Unmarshal([]byte{2, 1}, &o); err == nil {
+		t.Fatalf("option tag of 2 should error")
+	}
+}
+
+func TestDecoder_ReadLengthOverflow(t *testing.T) {
+	// 2^32-1 only overflows int on 32-bit platforms.
+	data := []byte{0xff, 0xff, 0xff, 0xff, 0x0f}
+	n, err := bcs.NewBytesDecoder(data).ReadLength()
+	if math.MaxInt > math.MaxUint32 {
+		if err != nil || uint64(n) != math.MaxUint32 {
+			t.Fatalf("want length %d, got %d, %v", uint32(math.MaxUint32), n, err)
+		}
+		return
+	}
+	if err == nil {
+		t.Fatalf("want overflow error, got length %d", n)
+	}
+
+	var b []byte
+	if _, err := bcs.Unmarshal(data, &b); err == nil {
+		t.Fatalf("want overflow error when decoding []byte")
+	}
+}
@@ -0,0 +1,58 @@
+package bcs
+
+// Raw holds the encoded bytes of a value of type T, like [encoding/json.RawMessage].
+//
+// When decoding, Raw records the exact bytes the value occupies in the input without keeping the
+// decoded value, see [Skip] for how the value is skipped. When encoding, the bytes are written verbatim.
+// This allows forwarding a signed payload byte-for-byte, or deferring the decoding of a large nested value.
+// An empty Raw is encoded as the zero value of T, so the output can always be decoded.
+//
+//	type Transaction struct {
+//	  Sender  [32]byte
+//	  Payload bcs.Raw[Payload] // decoded later with Payload.Decode
+//	}
+type Raw[T any] []byte
+
+var (
+	_ MarshalerTo     = Raw[int8]{}
+	_ UnmarshalerFrom = (*Raw[int8])(nil)
+)
+
+// NewRaw encodes v into a [Raw].
+func NewRaw[T any](v T) (Raw[T], error) {
+	b, err := Marshal(&v)
+	if err != nil {
+		return nil, err
+	}
+
+	return Raw[T](b), nil
+}
+
+// Decode decodes the raw bytes into a value of type T. All the bytes must be consumed.
+func (r Raw[T]) Decode() (T, error) {
+	var v T
+	err := UnmarshalAll(r, &v)
+	return v, err
+}
+
+func (r Raw[T]) MarshalBCSTo(e *Encoder) error {
+	if len(r) == 0 {
+		var v T
+		return e.Encode(&v)
+	}
+
+	return e.WriteFixedBytes(r)
+}
+
+func (r *Raw[T]) UnmarshalBCSFrom(d *Decoder) error {
+	start := d.startCapture()
+	err := Skip[T](d)
+	b := d.stopCapture(start)
+	if err != nil {
+		return err
+	}
+
+	*r = b
+
+	return nil
+}
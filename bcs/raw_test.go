@@ -0,0 +1,173 @@
+package bcs_test
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+type Payload struct {
+	Function string
+	Args     [][]byte
+	Enum     *NestedEnum
+	Amount   bcs.Uint128
+	Extra    *AnotherStruct `bcs:"optional"`
+}
+
+type Envelope struct {
+	Sender  [4]byte
+	Payload bcs.Raw[Payload]
+	Nonce   uint32
+}
+
+type RawEnvelope struct {
+	Sender  [4]byte
+	Payload Payload
+	Nonce   uint32
+}
+
+var rawPayload = Payload{
+	Function: "transfer",
+	Args:     [][]byte{{1, 2}, {3}},
+	Enum:     &NestedEnum{V0: &EnumExample{V4: &AnotherStruct{S: "abc"}}},
+	Amount:   *bcs.NewUint128FromUint64(7, 9),
+	Extra:    &AnotherStruct{S: "x"},
+}
+
+func TestRaw(t *testing.T) {
+	data := bcs.MustMarshal(&RawEnvelope{
+		Sender:  [4]byte{1, 2, 3, 4},
+		Payload: rawPayload,
+		Nonce:   11,
+	})
+	payloadBytes := bcs.MustMarshal(&rawPayload)
+
+	var env Envelope
+	n, err := bcs.Unmarshal(data, &env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(data) {
+		t.Fatalf("want parsed length: %d, got: %d", len(data), n)
+	}
+	if !slices.Equal(env.Payload, payloadBytes) {
+		t.Fatalf("want: %v\ngot:  %v", payloadBytes, []byte(env.Payload))
+	}
+	if env.Nonce != 11 {
+		t.Fatalf("want nonce 11, got %d", env.Nonce)
+	}
+
+	reencoded := bcs.MustMarshal(&env)
+	if !slices.Equal(reencoded, data) {
+		t.Fatalf("want: %v\ngot:  %v", data, reencoded)
+	}
+
+	p, err := env.Payload.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(bcs.MustMarshal(&p), payloadBytes) {
+		t.Fatalf("decoded payload doesn't match")
+	}
+
+	r, err := bcs.NewRaw(rawPayload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(r, payloadBytes) {
+		t.Fatalf("want: %v\ngot:  %v", payloadBytes, []byte(r))
+	}
+}
+
+func TestRaw_empty(t *testing.T) {
+	type withRaw struct {
+		A uint8
+		P bcs.Raw[uint64]
+	}
+
+	data, err := bcs.Marshal(withRaw{A: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{1, 0, 0, 0, 0, 0, 0, 0, 0}; !slices.Equal(data, want) {
+		t.Fatalf("want: %v\ngot:  %v", want, data)
+	}
+
+	var v withRaw
+	if err := bcs.UnmarshalAll(data, &v); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := v.P.Decode(); err != nil || p != 0 {
+		t.Fatalf("want zero payload, got %d, %v", p, err)
+	}
+}
+
+func TestRaw_nested(t *testing.T) {
+	type Inner struct {
+		A bcs.Raw[string]
+		B bcs.Raw[[]uint16]
+	}
+	data := bcs.MustMarshal(&struct {
+		A string
+		B []uint16
+	}{A: "ab", B: []uint16{1, 2}})
+
+	var v bcs.Raw[Inner]
+	if _, err := bcs.Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(v, data) {
+		t.Fatalf("want: %v\ngot:  %v", data, []byte(v))
+	}
+
+	inner, err := v.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(inner.A, []byte{2, 'a', 'b'}) || !slices.Equal(inner.B, []byte{2, 1, 0, 2, 0}) {
+		t.Fatalf("unexpected inner: %v", inner)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	data := append(bcs.MustMarshal(&rawPayload), 42)
+
+	d := bcs.NewDecoder(bytes.NewReader(data))
+	if err := bcs.Skip[Payload](d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Offset() != len(data)-1 {
+		t.Fatalf("want offset: %d, got: %d", len(data)-1, d.Offset())
+	}
+	if v, err := d.ReadU8(); err != nil || v != 42 {
+		t.Fatalf("want 42, got %v %v", v, err)
+	}
+
+	if err := bcs.Skip[Payload](bcs.NewDecoder(bytes.NewReader(data[:10]))); err == nil {
+		t.Fatalf("expect error for truncated input")
+	}
+
+	if err := bcs.Skip[any](bcs.NewDecoder(bytes.NewReader(data))); err == nil {
+		t.Fatalf("expect error for interface")
+	}
+}
+
+func TestSkip_optionalNonPointer(t *testing.T) {
+	type optionalInterface struct {
+		X any `bcs:"optional"`
+	}
+	data := []byte{1, 5}
+
+	if err := bcs.Skip[optionalInterface](bcs.NewBytesDecoder(data)); err == nil {
+		t.Fatal("want error for optional interface")
+	}
+
+	var v struct {
+		Inner bcs.Raw[optionalInterface]
+	}
+	if _, err := bcs.Unmarshal(data, &v); err == nil {
+		t.Fatal("want error for optional interface")
+	}
+}
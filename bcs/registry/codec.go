@@ -0,0 +1,428 @@
+package registry
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+// Codec returns the codec of the container of name, which encodes and decodes the values of the container
+// as generic values following the semantics of serde:
+//
+//   - UNIT and UNITSTRUCT are struct{}{}.
+//   - BOOL, U8 to U64, I8 to I64, STR and BYTES are the go types of the same sizes, such as uint32 and []byte.
+//   - U128 is [bcs.Uint128], and I128 is *[big.Int].
+//   - OPTION is *any, which is nil if the value is absent.
+//   - SEQ, TUPLE, TUPLEARRAY and TUPLESTRUCT are []any.
+//   - MAP is []MapEntry, in any order when encoding, and sorted by the encodings of the keys when decoding.
+//   - NEWTYPESTRUCT is the value it wraps.
+//   - STRUCT is map[string]any from the names of the fields to their values, see [bcs.StructOf].
+//   - ENUM is [bcs.EnumValue], whose value follows the same rules as the containers: struct{}{} for UNIT variants,
+//     the wrapped value for NEWTYPE variants, []any for TUPLE variants and map[string]any for STRUCT variants.
+//
+// F32, F64 and CHAR are not supported by bcs, and Codec errors if name depends on them.
+// The codec is built once for all the containers name depends on, so it is better to keep it around.
+func (r Registry) Codec(name string) (bcs.Codec[any], error) {
+	b := &builder{registry: r, codecs: make(map[string]*namedCodec)}
+	c, err := b.container(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// MapEntry is an entry of the values of MAP.
+type MapEntry struct {
+	Key   any
+	Value any
+}
+
+// builder builds the codecs of the containers in a registry.
+type builder struct {
+	registry Registry
+	codecs   map[string]*namedCodec
+}
+
+// namedCodec is the codec of a container, which is registered before it is built,
+// so containers referring to themselves can refer to it.
+type namedCodec struct {
+	bcs.Codec[any]
+}
+
+func (b *builder) container(name string) (*namedCodec, error) {
+	if c, ok := b.codecs[name]; ok {
+		return c, nil
+	}
+	cf, ok := b.registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown container %s", name)
+	}
+
+	c := &namedCodec{}
+	b.codecs[name] = c
+
+	var err error
+	switch cf.Kind {
+	case UnitStruct:
+		c.Codec = bcs.Any(bcs.Unit)
+	case NewTypeStruct:
+		c.Codec, err = b.format(cf.NewType)
+		c.Codec = nested(c.Codec)
+	case TupleStruct:
+		c.Codec, err = b.tuple(cf.Tuple)
+		c.Codec = nested(c.Codec)
+	case Struct:
+		c.Codec, err = b.structOf(name, cf.Fields)
+	case Enum:
+		c.Codec, err = b.enumOf(name, cf.Variants)
+	default:
+		err = fmt.Errorf("unknown container format %s", cf.Kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return c, nil
+}
+
+func (b *builder) format(f *Format) (bcs.Codec[any], error) {
+	if f == nil {
+		return nil, fmt.Errorf("missing format")
+	}
+
+	switch f.Kind {
+	case Unit:
+		return bcs.Any(bcs.Unit), nil
+	case Bool:
+		return bcs.Any(bcs.Bool), nil
+	case I8:
+		return bcs.Any(bcs.CodecFor[int8]()), nil
+	case I16:
+		return bcs.Any(bcs.CodecFor[int16]()), nil
+	case I32:
+		return bcs.Any(bcs.CodecFor[int32]()), nil
+	case I64:
+		return bcs.Any(bcs.CodecFor[int64]()), nil
+	case I128:
+		return bcs.Any(i128), nil
+	case U8:
+		return bcs.Any(bcs.U8), nil
+	case U16:
+		return bcs.Any(bcs.U16), nil
+	case U32:
+		return bcs.Any(bcs.U32), nil
+	case U64:
+		return bcs.Any(bcs.U64), nil
+	case U128:
+		return bcs.Any(bcs.U128), nil
+	case Str:
+		return bcs.Any(bcs.String), nil
+	case Bytes:
+		return bcs.Any(bcs.Bytes), nil
+	case F32, F64, Char:
+		return nil, fmt.Errorf("%s is not supported by bcs", f.Kind)
+	case TypeName:
+		return b.container(f.Name)
+	case Option:
+		elem, err := b.format(f.Elem)
+		if err != nil {
+			return nil, err
+		}
+		return bcs.Any(bcs.OptionOf(elem)), nil
+	case Seq:
+		elem, err := b.format(f.Elem)
+		if err != nil {
+			return nil, err
+		}
+		return vec(bcs.Vec(elem)), nil
+	case TupleArray:
+		elem, err := b.format(f.Elem)
+		if err != nil {
+			return nil, err
+		}
+		return vec(bcs.Array(f.Size, elem)), nil
+	case Tuple:
+		return b.tuple(f.Elems)
+	case Map:
+		key, err := b.format(f.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := b.format(f.Elem)
+		if err != nil {
+			return nil, err
+		}
+		return mapOf(key, value), nil
+	default:
+		return nil, fmt.Errorf("unknown format %s", f.Kind)
+	}
+}
+
+func (b *builder) tuple(formats []Format) (bcs.Codec[any], error) {
+	elems := make([]bcs.Codec[any], len(formats))
+	for i := range formats {
+		c, err := b.format(&formats[i])
+		if err != nil {
+			return nil, fmt.Errorf("[%d]: %w", i, err)
+		}
+		elems[i] = c
+	}
+
+	return tupleOf(elems), nil
+}
+
+func (b *builder) fields(fields []Named[Format]) ([]bcs.StructField, error) {
+	r := make([]bcs.StructField, len(fields))
+	for i := range fields {
+		c, err := b.format(&fields[i].Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fields[i].Name, err)
+		}
+		r[i] = bcs.Field(fields[i].Name, c)
+	}
+
+	return r, nil
+}
+
+func (b *builder) structOf(name string, fields []Named[Format]) (bcs.Codec[any], error) {
+	if err := checkUnique(fields, "field"); err != nil {
+		return nil, err
+	}
+	f, err := b.fields(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return bcs.Any(bcs.StructOf(name, f...)), nil
+}
+
+func (b *builder) enumOf(name string, variants []Named[VariantFormat]) (bcs.Codec[any], error) {
+	if err := checkUnique(variants, "variant"); err != nil {
+		return nil, err
+	}
+
+	r := make([]bcs.EnumVariant, len(variants))
+	for i, v := range variants {
+		var c bcs.Codec[any]
+		var err error
+		switch v.Value.Kind {
+		case UnitVariant:
+			c = bcs.Any(bcs.Unit)
+		case NewTypeVariant:
+			c, err = b.format(v.Value.NewType)
+		case TupleVariant:
+			c, err = b.tuple(v.Value.Tuple)
+		case StructVariant:
+			if err = checkUnique(v.Value.Fields, "field"); err != nil {
+				break
+			}
+			var fields []bcs.StructField
+			fields, err = b.fields(v.Value.Fields)
+			c = bcs.Any(bcs.StructOf(name+"::"+v.Name, fields...))
+		default:
+			err = fmt.Errorf("unknown variant format %s", v.Value.Kind)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", v.Name, err)
+		}
+		r[i] = bcs.Variant(v.Name, c)
+	}
+
+	return bcs.Any(bcs.EnumOf(name, r...)), nil
+}
+
+// checkUnique checks the names are unique, which [bcs.StructOf] and [bcs.EnumOf] panic on.
+func checkUnique[T any](named []Named[T], what string) error {
+	seen := make(map[string]bool, len(named))
+	for _, n := range named {
+		if seen[n.Name] {
+			return fmt.Errorf("duplicate %s %s", what, n.Name)
+		}
+		seen[n.Name] = true
+	}
+
+	return nil
+}
+
+// nested counts the depth of the values of c, which are named containers in rust.
+func nested(c bcs.Codec[any]) bcs.Codec[any] {
+	if c == nil {
+		return nil
+	}
+
+	return bcs.CodecOf(
+		func(e *bcs.Encoder, v any) error {
+			if err := e.Enter(); err != nil {
+				return err
+			}
+			defer e.Leave()
+			return c.Encode(e, v)
+		},
+		func(d *bcs.Decoder) (any, error) {
+			if err := d.Enter(); err != nil {
+				return nil, err
+			}
+			defer d.Leave()
+			return c.Decode(d)
+		},
+	)
+}
+
+// vec erases the type of the values of c, which are []any.
+func vec(c bcs.Codec[[]any]) bcs.Codec[any] {
+	return bcs.CodecOf(
+		func(e *bcs.Encoder, v any) error {
+			s, ok := v.([]any)
+			if !ok {
+				return fmt.Errorf("expected []any, got %T", v)
+			}
+			return c.Encode(e, s)
+		},
+		func(d *bcs.Decoder) (any, error) {
+			return c.Decode(d)
+		},
+	)
+}
+
+// tupleOf is a tuple of the elements, which are encoded in order as []any.
+func tupleOf(elems []bcs.Codec[any]) bcs.Codec[any] {
+	return bcs.CodecOf(
+		func(e *bcs.Encoder, v any) error {
+			s, ok := v.([]any)
+			if !ok {
+				return fmt.Errorf("expected []any, got %T", v)
+			}
+			if len(s) != len(elems) {
+				return fmt.Errorf("expected %d elements, got %d", len(elems), len(s))
+			}
+			for i, c := range elems {
+				if err := c.Encode(e, s[i]); err != nil {
+					return fmt.Errorf("[%d]: %w", i, err)
+				}
+			}
+			return nil
+		},
+		func(d *bcs.Decoder) (any, error) {
+			r := make([]any, len(elems))
+			for i, c := range elems {
+				v, err := c.Decode(d)
+				if err != nil {
+					return nil, fmt.Errorf("[%d]: %w", i, err)
+				}
+				r[i] = v
+			}
+			return r, nil
+		},
+	)
+}
+
+// mapOf is a map as []MapEntry, which is encoded like [bcs.MapOf], but the keys don't need to be comparable in go.
+func mapOf(key, value bcs.Codec[any]) bcs.Codec[any] {
+	type entry struct {
+		key   []byte
+		value any
+	}
+
+	return bcs.CodecOf(
+		func(e *bcs.Encoder, v any) error {
+			m, ok := v.([]MapEntry)
+			if !ok {
+				return fmt.Errorf("expected []MapEntry, got %T", v)
+			}
+
+			entries := make([]entry, len(m))
+			for i, me := range m {
+				k, err := bcs.MarshalWith(key, me.Key)
+				if err != nil {
+					return fmt.Errorf("key %v: %w", me.Key, err)
+				}
+				entries[i] = entry{key: k, value: me.Value}
+			}
+			slices.SortFunc(entries, func(a, b entry) int {
+				return bytes.Compare(a.key, b.key)
+			})
+
+			if err := e.WriteLength(len(entries)); err != nil {
+				return err
+			}
+			for i, en := range entries {
+				if i > 0 && bytes.Equal(entries[i-1].key, en.key) {
+					return fmt.Errorf("duplicate key %x", en.key)
+				}
+				if err := e.WriteFixedBytes(en.key); err != nil {
+					return err
+				}
+				if err := value.Encode(e, en.value); err != nil {
+					return fmt.Errorf("value of key %x: %w", en.key, err)
+				}
+			}
+			return nil
+		},
+		func(d *bcs.Decoder) (any, error) {
+			size, err := d.ReadLength()
+			if err != nil {
+				return nil, err
+			}
+
+			r := make([]MapEntry, 0, min(size, 1024))
+			var prev []byte
+			for i := 0; i < size; i++ {
+				k, err := key.Decode(d)
+				if err != nil {
+					return nil, fmt.Errorf("key %d: %w", i, err)
+				}
+				// the key is encoded again to check the keys are sorted, so the encoding is canonical.
+				encoded, err := bcs.MarshalWith(key, k)
+				if err != nil {
+					return nil, fmt.Errorf("key %d: %w", i, err)
+				}
+				if i > 0 && bytes.Compare(prev, encoded) >= 0 {
+					return nil, fmt.Errorf("key %d: keys are not sorted or are duplicated", i)
+				}
+				prev = encoded
+
+				v, err := value.Decode(d)
+				if err != nil {
+					return nil, fmt.Errorf("value of key %v: %w", k, err)
+				}
+				r = append(r, MapEntry{Key: k, Value: v})
+			}
+			return r, nil
+		},
+	)
+}
+
+// i128 is I128 as *[big.Int], which is encoded as 16 bytes of two's complement in little endian.
+var i128 bcs.Codec[*big.Int] = bcs.Transform(bcs.FixedBytes(16),
+	func(b []byte) (*big.Int, error) {
+		be := slices.Clone(b)
+		slices.Reverse(be)
+		v := new(big.Int).SetBytes(be)
+		if be[0]&0x80 != 0 {
+			v.Sub(v, twoTo128)
+		}
+		return v, nil
+	},
+	func(v *big.Int) ([]byte, error) {
+		if v == nil || v.Cmp(minI128) < 0 || v.Cmp(maxI128) > 0 {
+			return nil, fmt.Errorf("%v is out of range of i128", v)
+		}
+		u := new(big.Int).Set(v)
+		if u.Sign() < 0 {
+			u.Add(u, twoTo128)
+		}
+		b := u.FillBytes(make([]byte, 16))
+		slices.Reverse(b)
+		return b, nil
+	},
+)
+
+var (
+	twoTo128 = new(big.Int).Lsh(big.NewInt(1), 128)
+	maxI128  = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 127), big.NewInt(1))
+	minI128  = new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), 127))
+)
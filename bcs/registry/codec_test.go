@@ -0,0 +1,212 @@
+package registry_test
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+	"github.com/fardream/go-bcs/bcs/registry"
+)
+
+// the go types with the same layouts as the containers of testRegistry.
+type (
+	choice struct {
+		A *struct{}
+		B *uint64
+		C *struct{ X uint8 }
+		D *struct {
+			B bool
+			S string
+		}
+	}
+	pair struct {
+		A int8
+		B uint16
+	}
+	entry struct {
+		Key   string
+		Value bcs.Uint128
+	}
+	test struct {
+		A      []uint32
+		B      struct{ A, B uint64 }
+		C      choice
+		D      *[]byte `bcs:"optional"`
+		E      []entry
+		ID     [4]byte
+		Marker struct{}
+		Pair   pair
+	}
+)
+
+func (choice) IsBcsEnum() {}
+
+func u128(v uint64) bcs.Uint128 {
+	u, _ := bcs.NewUint128FromBigInt(new(big.Int).SetUint64(v))
+	return *u
+}
+
+func mustCodec(t *testing.T, name string) bcs.Codec[any] {
+	t.Helper()
+	r, err := registry.Parse([]byte(testRegistry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := r.Codec(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestCodec(t *testing.T) {
+	c := mustCodec(t, "Test")
+
+	d := []byte{1, 2}
+	dv := any(d)
+	want := bcs.MustMarshal(test{
+		A: []uint32{1, 2},
+		B: struct{ A, B uint64 }{A: 3, B: 4},
+		C: choice{D: &struct {
+			B bool
+			S string
+		}{B: true, S: "d"}},
+		D:    &d,
+		E:    []entry{{Key: "a", Value: u128(2)}, {Key: "b", Value: u128(1)}},
+		ID:   [4]byte{5, 6, 7, 8},
+		Pair: pair{A: -1, B: 9},
+	})
+
+	v := map[string]any{
+		"a": []any{uint32(1), uint32(2)},
+		"b": []any{int64(3), uint64(4)},
+		"c": bcs.EnumValue{Variant: "D", Value: []any{true, "d"}},
+		"d": &dv,
+		// the keys are sorted when encoding.
+		"e": []registry.MapEntry{
+			{Key: "b", Value: u128(1)},
+			{Key: "a", Value: u128(2)},
+		},
+		"id":     []any{uint8(5), uint8(6), uint8(7), uint8(8)},
+		"marker": struct{}{},
+		"pair":   []any{int8(-1), uint16(9)},
+	}
+	got, err := bcs.MarshalWith(c, any(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("want: %v\ngot:  %v", want, got)
+	}
+
+	decoded, err := bcs.UnmarshalWith(c, got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v["e"] = []registry.MapEntry{v["e"].([]registry.MapEntry)[1], v["e"].([]registry.MapEntry)[0]}
+	if !reflect.DeepEqual(decoded, any(v)) {
+		t.Fatalf("want: %#v\ngot:  %#v", v, decoded)
+	}
+
+	// the keys of the map are swapped.
+	swapped := bytes.Clone(want)
+	i := bytes.Index(swapped, []byte{2, 1, 'a'})
+	copy(swapped[i+1:], want[i+1+18:i+1+36])
+	copy(swapped[i+1+18:], want[i+1:i+1+18])
+	if _, err := bcs.UnmarshalWith(c, swapped); err == nil {
+		t.Fatal("want error for unsorted keys")
+	}
+}
+
+// TestCodec_vector checks the codecs of containers are one element of a vector started by BeginVector.
+func TestCodec_vector(t *testing.T) {
+	c := mustCodec(t, "Test")
+	pairCodec := mustCodec(t, "Pair")
+
+	var buf bytes.Buffer
+	e := bcs.NewEncoder(&buf)
+	if err := e.BeginVector(2); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []any{[]any{int8(1), uint16(2)}, []any{int8(-1), uint16(3)}} {
+		if err := pairCodec.Encode(e, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.EndVector(); err != nil {
+		t.Fatal(err)
+	}
+	if want := bcs.MustMarshal([]pair{{A: 1, B: 2}, {A: -1, B: 3}}); !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("want: %v\ngot:  %v", want, buf.Bytes())
+	}
+
+	// the fields of the struct, including the nested containers, are not elements of the vector.
+	buf.Reset()
+	if err := e.BeginVector(1); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := bcs.UnmarshalWith(c, bcs.MustMarshal(test{C: choice{A: &struct{}{}}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Encode(e, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.EndVector(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCodec_recursive(t *testing.T) {
+	c := mustCodec(t, "List")
+
+	minusOne := big.NewInt(-1)
+	list := bcs.EnumValue{Variant: "Cons", Value: []any{minusOne, bcs.EnumValue{Variant: "Nil", Value: struct{}{}}}}
+	b, err := bcs.MarshalWith(c, any(list))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append([]byte{1}, bytes.Repeat([]byte{0xff}, 16)...)
+	if !bytes.Equal(b, append(want, 0)) {
+		t.Fatalf("unexpected encoding: %v", b)
+	}
+
+	decoded, err := bcs.UnmarshalWith(c, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := decoded.(bcs.EnumValue).Value.([]any)[0].(*big.Int); v.Cmp(minusOne) != 0 {
+		t.Fatalf("want -1, got %v", v)
+	}
+
+	tooLarge := bcs.EnumValue{Variant: "Cons", Value: []any{new(big.Int).Lsh(big.NewInt(1), 127), list}}
+	if _, err := bcs.MarshalWith(c, any(tooLarge)); err == nil {
+		t.Fatal("want error for i128 out of range")
+	}
+}
+
+func TestCodec_invalid(t *testing.T) {
+	r, err := registry.Parse([]byte(`
+Float:
+  STRUCT:
+    - x: F64
+Missing:
+  NEWTYPESTRUCT:
+    TYPENAME: Unknown
+Duplicate:
+  STRUCT:
+    - x: U8
+    - x: U16
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Float", "Missing", "Duplicate", "Undefined"} {
+		if _, err := r.Codec(name); err == nil {
+			t.Errorf("want error for %s", name)
+		}
+	}
+}
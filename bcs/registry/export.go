@@ -0,0 +1,262 @@
+package registry
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/fardream/go-bcs/bcs"
+	"github.com/fardream/go-bcs/internal/bcstag"
+)
+
+var (
+	enumType            = reflect.TypeFor[bcs.Enum]()
+	uint128Type         = reflect.TypeFor[bcs.Uint128]()
+	marshalerType       = reflect.TypeFor[bcs.Marshaler]()
+	marshalerToType     = reflect.TypeFor[bcs.MarshalerTo]()
+	unmarshalerType     = reflect.TypeFor[bcs.Unmarshaler]()
+	unmarshalerFromType = reflect.TypeFor[bcs.UnmarshalerFrom]()
+)
+
+// Export returns the registry of the types and the named structs they contain, describing how [bcs.Marshal]
+// encodes them in the schema version, see [bcs.Encoder.SetVersion]. The registry can be used by serde-generate
+// to generate the same types in rust and other languages.
+//
+// Named structs are containers of the same names, STRUCT with the go names of the fields, or UNITSTRUCT if no fields
+// are encoded. [bcs.Enum] are ENUM, whose variants are named after the fields, and their indices are the indices
+// of the fields. The unexported and ignored fields before the last variant are UNIT variants, so the indices of the
+// following variants are kept, although they cannot be decoded in go. The variants are UNIT for *struct{},
+// STRUCT for pointers to anonymous structs, and NEWTYPE for other pointers. Like [bcs.Marshal], structs whose
+// IsBcsEnum has a pointer receiver are ENUM when used through pointers, and Export errors if they are also used
+// as values, which are encoded as structs.
+//
+// Other types are written where they are used: []byte is BYTES, arrays are TUPLEARRAY, optional fields
+// are OPTION, [bcs.Uint128] is U128, and anonymous structs are TUPLE of their fields. Named types
+// other than structs are the same as their underlying types, since they are encoded the same.
+//
+// Export errors if a type cannot be encoded, see [bcs.CheckType], or its format is unknown, such as types with
+// customized marshalers and interfaces.
+func Export(version int, types ...reflect.Type) (Registry, error) {
+	x := &exporter{registry: Registry{}, version: version, names: make(map[string]namedContainer)}
+	for _, t := range types {
+		if problems := bcs.CheckType(t); len(problems) > 0 {
+			messages := make([]string, len(problems))
+			for i, p := range problems {
+				messages[i] = p.String()
+			}
+			return nil, fmt.Errorf("%s cannot be encoded: %s", t, strings.Join(messages, "; "))
+		}
+		if _, err := x.format(t); err != nil {
+			return nil, err
+		}
+	}
+
+	return x.registry, nil
+}
+
+// exporter collects the containers of the types.
+type exporter struct {
+	registry Registry
+	version  int
+	// names are the types of the containers, which are registered before their fields are exported,
+	// so recursive types refer to themselves by names.
+	names map[string]namedContainer
+}
+
+// namedContainer is the type of a container, and whether it is encoded as an enum.
+type namedContainer struct {
+	typ  reflect.Type
+	enum bool
+}
+
+// primitiveKinds are the formats of the kinds encoded as primitives.
+var primitiveKinds = map[reflect.Kind]FormatKind{
+	reflect.Bool:   Bool,
+	reflect.Int8:   I8,
+	reflect.Int16:  I16,
+	reflect.Int32:  I32,
+	reflect.Int64:  I64,
+	reflect.Uint8:  U8,
+	reflect.Uint16: U16,
+	reflect.Uint32: U32,
+	reflect.Uint64: U64,
+	reflect.String: Str,
+}
+
+func (x *exporter) format(t reflect.Type) (*Format, error) {
+	if t == uint128Type {
+		return &Format{Kind: U128}, nil
+	}
+	if hasCustomMarshaler(t) {
+		return nil, fmt.Errorf("%s has customized marshalers, whose format is unknown", t)
+	}
+
+	if kind, ok := primitiveKinds[t.Kind()]; ok {
+		return &Format{Kind: kind}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		// the encoder treats a pointer as an enum when the pointer implements [bcs.Enum],
+		// which includes enums whose IsBcsEnum has a pointer receiver.
+		if elem := t.Elem(); elem.Kind() == reflect.Struct && elem.Name() != "" && t.Implements(enumType) {
+			return x.container(elem, true)
+		}
+		return x.format(t.Elem())
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Format{Kind: Bytes}, nil
+		}
+		elem, err := x.format(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Format{Kind: Seq, Elem: elem}, nil
+	case reflect.Array:
+		elem, err := x.format(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Format{Kind: TupleArray, Elem: elem, Size: t.Len()}, nil
+	case reflect.Struct:
+		if t.Name() != "" {
+			return x.container(t, t.Implements(enumType))
+		}
+		fields, err := x.fields(t)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) == 0 {
+			return &Format{Kind: Unit}, nil
+		}
+		elems := make([]Format, len(fields))
+		for i, f := range fields {
+			elems[i] = f.Value
+		}
+		return &Format{Kind: Tuple, Elems: elems}, nil
+	default:
+		return nil, fmt.Errorf("the format of %s is unknown", t)
+	}
+}
+
+// container registers the named struct t as an enum or a struct, and returns the format referring to it.
+func (x *exporter) container(t reflect.Type, enum bool) (*Format, error) {
+	name := t.Name()
+	ref := &Format{Kind: TypeName, Name: name}
+	if prev, ok := x.names[name]; ok {
+		if prev.typ != t {
+			return nil, fmt.Errorf("%s and %s have the same name", prev.typ, t)
+		}
+		if prev.enum != enum {
+			return nil, fmt.Errorf("%s is encoded both as an enum through pointers and as a struct", t)
+		}
+		return ref, nil
+	}
+	x.names[name] = namedContainer{typ: t, enum: enum}
+
+	var c ContainerFormat
+	if enum {
+		variants, err := x.variants(t)
+		if err != nil {
+			return nil, err
+		}
+		c = ContainerFormat{Kind: Enum, Variants: variants}
+	} else {
+		fields, err := x.fields(t)
+		if err != nil {
+			return nil, err
+		}
+		c = ContainerFormat{Kind: Struct, Fields: fields}
+		if len(fields) == 0 {
+			c = ContainerFormat{Kind: UnitStruct}
+		}
+	}
+	x.registry[name] = c
+
+	return ref, nil
+}
+
+// fields returns the fields of struct t encoded in the version of the exporter.
+func (x *exporter) fields(t reflect.Type) ([]Named[Format], error) {
+	var r []Named[Format]
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag, err := bcstag.Parse(field.Tag.Get(bcstag.Name))
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t, field.Name, err)
+		}
+		if tag.Ignore || !bcstag.InVersion(x.version, tag.Since, tag.Until) {
+			continue
+		}
+
+		f, err := x.format(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t, field.Name, err)
+		}
+		if tag.Optional {
+			f = &Format{Kind: Option, Elem: f}
+		}
+		r = append(r, Named[Format]{Name: field.Name, Value: *f})
+	}
+
+	return r, nil
+}
+
+// variants returns the variants of enum t, indexed by the indices of the fields.
+func (x *exporter) variants(t reflect.Type) ([]Named[VariantFormat], error) {
+	var r []Named[VariantFormat]
+	last := -1
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		r = append(r, Named[VariantFormat]{Name: field.Name, Value: VariantFormat{Kind: UnitVariant}})
+		if !field.IsExported() {
+			continue
+		}
+		tag, err := bcstag.Parse(field.Tag.Get(bcstag.Name))
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t, field.Name, err)
+		}
+		if tag.Ignore {
+			continue
+		}
+		last = i
+
+		if field.Type.Kind() != reflect.Pointer {
+			return nil, fmt.Errorf("%s.%s: the format of %s is unknown", t, field.Name, field.Type)
+		}
+		elem := field.Type.Elem()
+		if elem.Kind() == reflect.Struct && elem.Name() == "" && !hasCustomMarshaler(elem) {
+			fields, err := x.fields(elem)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", t, field.Name, err)
+			}
+			if len(fields) > 0 {
+				r[i].Value = VariantFormat{Kind: StructVariant, Fields: fields}
+			}
+			continue
+		}
+
+		f, err := x.format(elem)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t, field.Name, err)
+		}
+		r[i].Value = VariantFormat{Kind: NewTypeVariant, NewType: f}
+	}
+
+	return r[:last+1], nil
+}
+
+// hasCustomMarshaler checks if t or its pointer implements any of the customized marshalers or unmarshalers.
+func hasCustomMarshaler(t reflect.Type) bool {
+	pt := reflect.PointerTo(t)
+	for _, i := range []reflect.Type{marshalerType, marshalerToType, unmarshalerType, unmarshalerFromType} {
+		if t.Implements(i) || pt.Implements(i) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,261 @@
+package registry_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+	"github.com/fardream/go-bcs/bcs/registry"
+)
+
+type (
+	Address [4]byte
+
+	Account struct {
+		Owner   Address
+		Balance bcs.Uint128
+		Coins   []Coin
+		Memo    *string `bcs:"optional"`
+		Tag     *uint16 `bcs:"optional"`
+		Key     []byte
+		Nonce   uint64 `bcs:"since=2"`
+		Legacy  uint8  `bcs:"until=1"`
+		Skipped uint8  `bcs:"-"`
+		private uint8
+	}
+
+	Coin struct {
+		Value uint64
+		Pair  struct {
+			A int8
+			B bool
+		}
+	}
+
+	Marker struct{}
+
+	Action struct {
+		Stop     *struct{}
+		Transfer *struct {
+			To     Address
+			Amount uint64
+		}
+		Ignored *uint8 `bcs:"-"`
+		Account *Account
+		Next    *Action
+		Mark    *Marker
+		Unused  *uint8 `bcs:"-"`
+	}
+)
+
+func (Action) IsBcsEnum() {}
+
+const wantRegistry = `---
+Account:
+  STRUCT:
+    - Owner:
+        TUPLEARRAY:
+          CONTENT: U8
+          SIZE: 4
+    - Balance: U128
+    - Coins:
+        SEQ:
+          TYPENAME: Coin
+    - Memo:
+        OPTION: STR
+    - Tag:
+        OPTION: U16
+    - Key: BYTES
+    - Nonce: U64
+Action:
+  ENUM:
+    0:
+      Stop: UNIT
+    1:
+      Transfer:
+        STRUCT:
+          - To:
+              TUPLEARRAY:
+                CONTENT: U8
+                SIZE: 4
+          - Amount: U64
+    2:
+      Ignored: UNIT
+    3:
+      Account:
+        NEWTYPE:
+          TYPENAME: Account
+    4:
+      Next:
+        NEWTYPE:
+          TYPENAME: Action
+    5:
+      Mark:
+        NEWTYPE:
+          TYPENAME: Marker
+Coin:
+  STRUCT:
+    - Value: U64
+    - Pair:
+        TUPLE:
+          - I8
+          - BOOL
+Marker: UNITSTRUCT
+`
+
+func TestExport(t *testing.T) {
+	r, err := registry.Export(bcs.LatestVersion, reflect.TypeFor[Action]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := r.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != wantRegistry {
+		t.Fatalf("want:\n%s\ngot:\n%s", wantRegistry, b)
+	}
+
+	r, err = registry.Export(1, reflect.TypeFor[Account]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range r["Account"].Fields {
+		names = append(names, f.Name)
+	}
+	if want := []string{"Owner", "Balance", "Coins", "Memo", "Tag", "Key", "Legacy"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("want fields %v in version 1, got %v", want, names)
+	}
+}
+
+// TestExport_codec checks the codecs of the exported registry decode the encodings of the go values,
+// and encode the decoded values back to the same bytes.
+func TestExport_codec(t *testing.T) {
+	r, err := registry.Export(bcs.LatestVersion, reflect.TypeFor[Action]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := r.Codec("Action")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	memo := "memo"
+	tag := uint16(6)
+	for _, v := range []Action{
+		{Stop: &struct{}{}},
+		{Transfer: &struct {
+			To     Address
+			Amount uint64
+		}{To: Address{1, 2, 3, 4}, Amount: 5}},
+		{Next: &Action{Account: &Account{
+			Coins: []Coin{{Value: 1}, {Value: 2, Pair: struct {
+				A int8
+				B bool
+			}{A: -3, B: true}}},
+			Memo: &memo,
+			Tag:  &tag,
+			Key:  []byte{7, 8},
+		}}},
+		{Mark: &Marker{}},
+	} {
+		want := bcs.MustMarshal(v)
+		decoded, err := bcs.UnmarshalWith(c, want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := bcs.MarshalWith(c, decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("want: %v\ngot:  %v", want, got)
+		}
+	}
+}
+
+type (
+	withMap struct {
+		M map[string]uint8
+	}
+	withInterface struct {
+		I any
+	}
+	withBadVersion struct {
+		V uint8 `bcs:"since=x"`
+	}
+	// custom has a customized marshaler, whose format is unknown.
+	custom uint16
+)
+
+func (c custom) MarshalBCS() ([]byte, error) {
+	return []byte{uint8(c), uint8(c >> 8)}, nil
+}
+
+func TestExport_invalid(t *testing.T) {
+	for _, typ := range []reflect.Type{
+		reflect.TypeFor[withMap](),
+		reflect.TypeFor[withInterface](),
+		reflect.TypeFor[[]custom](),
+		reflect.TypeFor[withBadVersion](),
+	} {
+		if _, err := registry.Export(bcs.LatestVersion, typ); err == nil {
+			t.Errorf("want error for %s", typ)
+		}
+	}
+}
+
+type (
+	// PEnum is an enum whose IsBcsEnum has a pointer receiver, which is only an enum through pointers.
+	PEnum struct {
+		A *uint8
+		B *uint16
+	}
+
+	Holder struct {
+		E *PEnum
+	}
+
+	mixedHolder struct {
+		E *PEnum
+		V PEnum
+	}
+)
+
+func (*PEnum) IsBcsEnum() {}
+
+func TestExport_pointerReceiverEnum(t *testing.T) {
+	r, err := registry.Export(bcs.LatestVersion, reflect.TypeFor[Holder]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kind := r["PEnum"].Kind; kind != registry.Enum {
+		t.Fatalf("want PEnum exported as ENUM, got %s", kind)
+	}
+
+	c, err := r.Codec("Holder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := bcs.MustMarshal(Holder{E: &PEnum{B: new(uint16)}})
+	if !bytes.Equal(want, []byte{1, 0, 0}) {
+		t.Fatalf("want enum encoding [1 0 0], got %v", want)
+	}
+	decoded, err := bcs.UnmarshalWith(c, want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := bcs.MarshalWith(c, decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("want: %v\ngot:  %v", want, got)
+	}
+
+	if _, err := registry.Export(bcs.LatestVersion, reflect.TypeFor[mixedHolder]()); err == nil {
+		t.Fatal("want error for PEnum used both through a pointer and as a value")
+	}
+}
@@ -0,0 +1,341 @@
+package registry
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FormatKind is the kind of a [Format], which is also its tag in the registry files.
+type FormatKind string
+
+const (
+	Unit       FormatKind = "UNIT"
+	Bool       FormatKind = "BOOL"
+	I8         FormatKind = "I8"
+	I16        FormatKind = "I16"
+	I32        FormatKind = "I32"
+	I64        FormatKind = "I64"
+	I128       FormatKind = "I128"
+	U8         FormatKind = "U8"
+	U16        FormatKind = "U16"
+	U32        FormatKind = "U32"
+	U64        FormatKind = "U64"
+	U128       FormatKind = "U128"
+	F32        FormatKind = "F32"
+	F64        FormatKind = "F64"
+	Char       FormatKind = "CHAR"
+	Str        FormatKind = "STR"
+	Bytes      FormatKind = "BYTES"
+	TypeName   FormatKind = "TYPENAME"
+	Option     FormatKind = "OPTION"
+	Seq        FormatKind = "SEQ"
+	Map        FormatKind = "MAP"
+	Tuple      FormatKind = "TUPLE"
+	TupleArray FormatKind = "TUPLEARRAY"
+)
+
+// primitives are the kinds of formats written as plain strings.
+var primitives = []FormatKind{Unit, Bool, I8, I16, I32, I64, I128, U8, U16, U32, U64, U128, F32, F64, Char, Str, Bytes}
+
+// Format is the format of a value, the Format of serde-reflection.
+type Format struct {
+	Kind FormatKind
+	// Name is the name of the container of [TypeName].
+	Name string
+	// Elem is the format of the value of [Option], the elements of [Seq] and [TupleArray], and the values of [Map].
+	Elem *Format
+	// Key is the format of the keys of [Map].
+	Key *Format
+	// Elems are the formats of the elements of [Tuple].
+	Elems []Format
+	// Size is the number of elements of [TupleArray].
+	Size int
+}
+
+// mapFormat and tupleArrayFormat are the contents of [Map] and [TupleArray] in the registry files.
+type (
+	mapFormat struct {
+		Key   Format `yaml:"KEY"`
+		Value Format `yaml:"VALUE"`
+	}
+	tupleArrayFormat struct {
+		Content Format `yaml:"CONTENT"`
+		Size    int    `yaml:"SIZE"`
+	}
+)
+
+func (f *Format) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		kind := FormatKind(node.Value)
+		if !slices.Contains(primitives, kind) {
+			return fmt.Errorf("line %d: unknown format %s", node.Line, node.Value)
+		}
+		*f = Format{Kind: kind}
+		return nil
+	}
+
+	tag, value, err := singleEntry(node)
+	if err != nil {
+		return err
+	}
+
+	r := Format{Kind: FormatKind(tag)}
+	switch r.Kind {
+	case TypeName:
+		err = value.Decode(&r.Name)
+	case Option, Seq:
+		r.Elem = new(Format)
+		err = value.Decode(r.Elem)
+	case Map:
+		var m mapFormat
+		err = value.Decode(&m)
+		r.Key, r.Elem = &m.Key, &m.Value
+	case Tuple:
+		err = value.Decode(&r.Elems)
+	case TupleArray:
+		var a tupleArrayFormat
+		err = value.Decode(&a)
+		r.Elem, r.Size = &a.Content, a.Size
+	default:
+		return fmt.Errorf("line %d: unknown format %s", node.Line, tag)
+	}
+	if err != nil {
+		return err
+	}
+
+	*f = r
+	return nil
+}
+
+func (f Format) MarshalYAML() (any, error) {
+	switch f.Kind {
+	case TypeName:
+		return map[FormatKind]string{f.Kind: f.Name}, nil
+	case Option, Seq:
+		return map[FormatKind]*Format{f.Kind: f.Elem}, nil
+	case Map:
+		if f.Key == nil || f.Elem == nil {
+			return nil, fmt.Errorf("map without the formats of keys and values")
+		}
+		return map[FormatKind]mapFormat{f.Kind: {Key: *f.Key, Value: *f.Elem}}, nil
+	case Tuple:
+		return map[FormatKind][]Format{f.Kind: f.Elems}, nil
+	case TupleArray:
+		if f.Elem == nil {
+			return nil, fmt.Errorf("tuple array without the format of elements")
+		}
+		return map[FormatKind]tupleArrayFormat{f.Kind: {Content: *f.Elem, Size: f.Size}}, nil
+	default:
+		if !slices.Contains(primitives, f.Kind) {
+			return nil, fmt.Errorf("unknown format %s", f.Kind)
+		}
+		return string(f.Kind), nil
+	}
+}
+
+// Named is a named field of a struct, or a variant of an enum.
+type Named[T any] struct {
+	Name  string
+	Value T
+}
+
+func (n *Named[T]) UnmarshalYAML(node *yaml.Node) error {
+	name, value, err := singleEntry(node)
+	if err != nil {
+		return err
+	}
+	n.Name = name
+
+	return value.Decode(&n.Value)
+}
+
+func (n Named[T]) MarshalYAML() (any, error) {
+	return map[string]T{n.Name: n.Value}, nil
+}
+
+// ContainerKind is the kind of a [ContainerFormat], which is also its tag in the registry files.
+type ContainerKind string
+
+const (
+	UnitStruct    ContainerKind = "UNITSTRUCT"
+	NewTypeStruct ContainerKind = "NEWTYPESTRUCT"
+	TupleStruct   ContainerKind = "TUPLESTRUCT"
+	Struct        ContainerKind = "STRUCT"
+	Enum          ContainerKind = "ENUM"
+)
+
+// ContainerFormat is the format of a named type, the ContainerFormat of serde-reflection.
+type ContainerFormat struct {
+	Kind ContainerKind
+	// NewType is the format of the value of [NewTypeStruct].
+	NewType *Format
+	// Tuple is the formats of the elements of [TupleStruct].
+	Tuple []Format
+	// Fields are the fields of [Struct].
+	Fields []Named[Format]
+	// Variants are the variants of [Enum], indexed by their positions.
+	Variants []Named[VariantFormat]
+}
+
+func (c *ContainerFormat) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		if ContainerKind(node.Value) != UnitStruct {
+			return fmt.Errorf("line %d: unknown container format %s", node.Line, node.Value)
+		}
+		*c = ContainerFormat{Kind: UnitStruct}
+		return nil
+	}
+
+	tag, value, err := singleEntry(node)
+	if err != nil {
+		return err
+	}
+
+	r := ContainerFormat{Kind: ContainerKind(tag)}
+	switch r.Kind {
+	case NewTypeStruct:
+		r.NewType = new(Format)
+		err = value.Decode(r.NewType)
+	case TupleStruct:
+		err = value.Decode(&r.Tuple)
+	case Struct:
+		err = value.Decode(&r.Fields)
+	case Enum:
+		r.Variants, err = decodeVariants(value)
+	default:
+		return fmt.Errorf("line %d: unknown container format %s", node.Line, tag)
+	}
+	if err != nil {
+		return err
+	}
+
+	*c = r
+	return nil
+}
+
+// decodeVariants decodes the variants of an enum, which are keyed by their indices.
+func decodeVariants(node *yaml.Node) ([]Named[VariantFormat], error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: variants must be a mapping from the indices", node.Line)
+	}
+
+	variants := make([]Named[VariantFormat], len(node.Content)/2)
+	seen := make([]bool, len(variants))
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i]
+		index, err := strconv.Atoi(key.Value)
+		if err != nil || index < 0 || index >= len(variants) || seen[index] {
+			return nil, fmt.Errorf("line %d: invalid variant index %s, indices must be from 0 to %d without gaps", key.Line, key.Value, len(variants)-1)
+		}
+		seen[index] = true
+		if err := node.Content[i+1].Decode(&variants[index]); err != nil {
+			return nil, err
+		}
+	}
+
+	return variants, nil
+}
+
+func (c ContainerFormat) MarshalYAML() (any, error) {
+	switch c.Kind {
+	case UnitStruct:
+		return string(c.Kind), nil
+	case NewTypeStruct:
+		return map[ContainerKind]*Format{c.Kind: c.NewType}, nil
+	case TupleStruct:
+		return map[ContainerKind][]Format{c.Kind: c.Tuple}, nil
+	case Struct:
+		return map[ContainerKind][]Named[Format]{c.Kind: c.Fields}, nil
+	case Enum:
+		variants := make(map[int]Named[VariantFormat], len(c.Variants))
+		for i, v := range c.Variants {
+			variants[i] = v
+		}
+		return map[ContainerKind]map[int]Named[VariantFormat]{c.Kind: variants}, nil
+	default:
+		return nil, fmt.Errorf("unknown container format %s", c.Kind)
+	}
+}
+
+// VariantKind is the kind of a [VariantFormat], which is also its tag in the registry files.
+type VariantKind string
+
+const (
+	UnitVariant    VariantKind = "UNIT"
+	NewTypeVariant VariantKind = "NEWTYPE"
+	TupleVariant   VariantKind = "TUPLE"
+	StructVariant  VariantKind = "STRUCT"
+)
+
+// VariantFormat is the format of a variant of an enum, the VariantFormat of serde-reflection.
+type VariantFormat struct {
+	Kind VariantKind
+	// NewType is the format of the value of [NewTypeVariant].
+	NewType *Format
+	// Tuple is the formats of the elements of [TupleVariant].
+	Tuple []Format
+	// Fields are the fields of [StructVariant].
+	Fields []Named[Format]
+}
+
+func (v *VariantFormat) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		if VariantKind(node.Value) != UnitVariant {
+			return fmt.Errorf("line %d: unknown variant format %s", node.Line, node.Value)
+		}
+		*v = VariantFormat{Kind: UnitVariant}
+		return nil
+	}
+
+	tag, value, err := singleEntry(node)
+	if err != nil {
+		return err
+	}
+
+	r := VariantFormat{Kind: VariantKind(tag)}
+	switch r.Kind {
+	case NewTypeVariant:
+		r.NewType = new(Format)
+		err = value.Decode(r.NewType)
+	case TupleVariant:
+		err = value.Decode(&r.Tuple)
+	case StructVariant:
+		err = value.Decode(&r.Fields)
+	default:
+		return fmt.Errorf("line %d: unknown variant format %s", node.Line, tag)
+	}
+	if err != nil {
+		return err
+	}
+
+	*v = r
+	return nil
+}
+
+func (v VariantFormat) MarshalYAML() (any, error) {
+	switch v.Kind {
+	case UnitVariant:
+		return string(v.Kind), nil
+	case NewTypeVariant:
+		return map[VariantKind]*Format{v.Kind: v.NewType}, nil
+	case TupleVariant:
+		return map[VariantKind][]Format{v.Kind: v.Tuple}, nil
+	case StructVariant:
+		return map[VariantKind][]Named[Format]{v.Kind: v.Fields}, nil
+	default:
+		return nil, fmt.Errorf("unknown variant format %s", v.Kind)
+	}
+}
+
+// singleEntry returns the key and the value of a mapping with a single entry, which is how serde
+// writes the variants of enums with values, such as the formats.
+func singleEntry(node *yaml.Node) (string, *yaml.Node, error) {
+	if node.Kind != yaml.MappingNode || len(node.Content) != 2 {
+		return "", nil, fmt.Errorf("line %d: expected a mapping with a single entry", node.Line)
+	}
+
+	return node.Content[0].Value, node.Content[1], nil
+}
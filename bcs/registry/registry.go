@@ -0,0 +1,53 @@
+// Package registry reads and writes the registries of serde-reflection, which describe the bcs layouts
+// of the types in rust, and are used by serde-generate to generate the types in other languages.
+//
+// A registry is a yaml (or json) file mapping the names of the containers to their formats:
+//
+//	Coin:
+//	  STRUCT:
+//	    - id:
+//	        TUPLEARRAY:
+//	          CONTENT: U8
+//	          SIZE: 32
+//	    - value: U64
+//
+// [Registry.Codec] builds the codecs encoding and decoding the values of the containers at runtime,
+// without go types. In the other direction, [Export] describes go types in a registry, so serde-generate
+// can generate the matching types in rust and other languages.
+package registry
+
+import (
+	"bytes"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Registry maps the names of the containers to their formats, the Registry of serde-reflection.
+type Registry map[string]ContainerFormat
+
+// Parse parses a registry in yaml or json.
+func Parse(data []byte) (Registry, error) {
+	var r Registry
+	if err := yaml.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Marshal writes the registry in yaml, in the same layout as serde-reflection.
+func (r Registry) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+
+	e := yaml.NewEncoder(&buf)
+	e.SetIndent(2)
+	if err := e.Encode(r); err != nil {
+		return nil, err
+	}
+	if err := e.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
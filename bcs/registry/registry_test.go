@@ -0,0 +1,123 @@
+package registry_test
+
+import (
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs/registry"
+)
+
+// testRegistry is in the layout written by serde-reflection.
+const testRegistry = `---
+Choice:
+  ENUM:
+    0:
+      A: UNIT
+    1:
+      B:
+        NEWTYPE: U64
+    2:
+      C:
+        STRUCT:
+          - x: U8
+    3:
+      D:
+        TUPLE:
+          - BOOL
+          - STR
+Id:
+  NEWTYPESTRUCT:
+    TUPLEARRAY:
+      CONTENT: U8
+      SIZE: 4
+List:
+  ENUM:
+    0:
+      Nil: UNIT
+    1:
+      Cons:
+        TUPLE:
+          - I128
+          - TYPENAME: List
+Marker: UNITSTRUCT
+Pair:
+  TUPLESTRUCT:
+    - I8
+    - U16
+Test:
+  STRUCT:
+    - a:
+        SEQ: U32
+    - b:
+        TUPLE:
+          - I64
+          - U64
+    - c:
+        TYPENAME: Choice
+    - d:
+        OPTION: BYTES
+    - e:
+        MAP:
+          KEY: STR
+          VALUE: U128
+    - id:
+        TYPENAME: Id
+    - marker:
+        TYPENAME: Marker
+    - pair:
+        TYPENAME: Pair
+`
+
+func TestParse(t *testing.T) {
+	r, err := registry.Parse([]byte(testRegistry))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	choice := r["Choice"]
+	if choice.Kind != registry.Enum || len(choice.Variants) != 4 || choice.Variants[2].Name != "C" {
+		t.Fatalf("unexpected enum: %+v", choice)
+	}
+	if c := choice.Variants[2].Value; c.Kind != registry.StructVariant || c.Fields[0].Name != "x" || c.Fields[0].Value.Kind != registry.U8 {
+		t.Fatalf("unexpected variant: %+v", c)
+	}
+	e := r["Test"].Fields[4].Value
+	if e.Kind != registry.Map || e.Key.Kind != registry.Str || e.Elem.Kind != registry.U128 {
+		t.Fatalf("unexpected map: %+v", e)
+	}
+	if id := r["Id"].NewType; id.Kind != registry.TupleArray || id.Size != 4 || id.Elem.Kind != registry.U8 {
+		t.Fatalf("unexpected tuple array: %+v", id)
+	}
+
+	b, err := r.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), testRegistry; got != want {
+		t.Fatalf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestParse_json(t *testing.T) {
+	r, err := registry.Parse([]byte(`{"Choice": {"ENUM": {"1": {"B": {"NEWTYPE": "U64"}}, "0": {"A": "UNIT"}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := r["Choice"].Variants; len(v) != 2 || v[0].Name != "A" || v[1].Value.NewType.Kind != registry.U64 {
+		t.Fatalf("unexpected variants: %+v", v)
+	}
+}
+
+func TestParse_invalid(t *testing.T) {
+	for _, s := range []string{
+		"A: STRUCTURE",
+		"A: {STRUCT: [{x: U9}]}",
+		"A: {STRUCT: [{x: {SEQ: U8, OPTION: U8}}]}",
+		"A: {ENUM: {0: {X: UNIT}, 2: {Y: UNIT}}}",
+		"A: {ENUM: {0: {X: UNIT}, 0: {Y: UNIT}}}",
+		"A: {ENUM: {0: {X: STRUCTURE}}}",
+	} {
+		if _, err := registry.Parse([]byte(s)); err == nil {
+			t.Errorf("want error for %s", s)
+		}
+	}
+}
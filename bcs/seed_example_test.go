@@ -0,0 +1,69 @@
+package bcs_test
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+// Samples is a list of u16, the length of which is not prefixed but
+// stored in the header of the message.
+type Samples []uint16
+
+func (s *Samples) UnmarshalBCSFrom(d *bcs.Decoder) error {
+	n, ok := d.Seed().(int)
+	if !ok {
+		return fmt.Errorf("Samples requires the length as seed, got %T", d.Seed())
+	}
+
+	*s = make(Samples, n)
+	for i := range *s {
+		v, err := d.ReadU16()
+		if err != nil {
+			return err
+		}
+		(*s)[i] = v
+	}
+
+	return nil
+}
+
+// Message is a header containing the number of samples, followed by the samples.
+type Message struct {
+	Count   uint8
+	Samples Samples
+}
+
+func (m *Message) UnmarshalBCSFrom(d *bcs.Decoder) error {
+	if _, err := d.Decode(&m.Count); err != nil {
+		return err
+	}
+
+	_, err := d.DecodeSeed(int(m.Count), &m.Samples)
+	return err
+}
+
+func ExampleDecoder_DecodeSeed() {
+	data := []byte{3, 1, 0, 2, 0, 3, 0}
+
+	var m Message
+	n, err := bcs.NewDecoder(bytes.NewReader(data)).Decode(&m)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(n, m.Count, m.Samples)
+
+	// The seed can also be provided by the caller for the top level value.
+	d := bcs.NewDecoder(bytes.NewReader(data[1:]))
+	d.SetSeed(2)
+	var s Samples
+	if _, err := d.Decode(&s); err != nil {
+		panic(err)
+	}
+
+	fmt.Println(s)
+	// Output: 7 3 [1 2 3]
+	// [1 2]
+}
@@ -0,0 +1,149 @@
+package bcs
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Skip advances the decoder past a value of type T without keeping the decoded value.
+//
+// The bytes are skipped by walking the type: the lengths of vectors, the option tags,
+// and the enum variants are read, but no memory is allocated for the content.
+// Types implementing [UnmarshalerFrom] or [Unmarshaler] cannot be skipped without
+// knowing their format, so they are decoded into a temporary value and discarded.
+func Skip[T any](d *Decoder) error {
+	return d.skip(reflect.TypeFor[T]())
+}
+
+// skip follows the same rules as decode, but for a type instead of a value.
+func (d *Decoder) skip(t reflect.Type) error {
+	return d.skipValue(planFor(t))
+}
+
+func (d *Decoder) skipValue(p *typePlan) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	if p.hasCustomUnmarshaler() {
+		return d.decodeValue(reflect.New(p.typ).Elem(), p)
+	}
+
+	if p.decodesAsEnum() {
+		if err := d.Enter(); err != nil {
+			return err
+		}
+		defer d.Leave()
+
+		return d.skipEnum(p.enumPlan())
+	}
+
+	switch p.kind {
+	case reflect.Pointer:
+		return d.skipValue(p.elem)
+
+	case reflect.Bool:
+		_, err := d.ReadBool()
+		return err
+	case reflect.Int8, reflect.Uint8, reflect.Int16, reflect.Uint16, reflect.Int32, reflect.Uint32, reflect.Int64, reflect.Uint64:
+		return d.discard(p.size)
+
+	case reflect.String:
+		size, err := d.ReadLength()
+		if err != nil {
+			return err
+		}
+		return d.discard(size)
+
+	case reflect.Slice:
+		size, err := d.ReadLength()
+		if err != nil {
+			return err
+		}
+		if p.elem.kind == reflect.Uint8 {
+			return d.discard(size)
+		}
+		for i := 0; i < size; i++ {
+			if err := d.skipValue(p.elem); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Array:
+		for i := 0; i < p.typ.Len(); i++ {
+			if err := d.skipValue(p.elem); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Struct:
+		if err := d.Enter(); err != nil {
+			return err
+		}
+		defer d.Leave()
+
+		return d.skipStruct(p)
+
+	case reflect.Chan, reflect.Func, reflect.Uintptr, reflect.UnsafePointer:
+		return nil
+
+	case reflect.Interface:
+		return fmt.Errorf("cannot skip interface %s without a value", p.typ.String())
+
+	default:
+		return fmt.Errorf("unsupported kind: %s", p.kind.String())
+	}
+}
+
+func (d *Decoder) skipStruct(p *typePlan) error {
+	for _, f := range p.fields {
+		if f.isVersioned() && !f.inVersion(d.version) {
+			continue
+		}
+		if f.tag.isOptional() {
+			isSome, err := d.ReadOptionTag()
+			if err != nil {
+				return err
+			}
+			if isSome {
+				elem, err := f.optionalElem()
+				if err != nil {
+					return err
+				}
+				if err := d.skipValue(elem); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if err := d.skipValue(f.plan); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *Decoder) skipEnum(p *typePlan) error {
+	if p.kind != reflect.Struct {
+		return fmt.Errorf("only support struct for Enum, got %s", p.kind.String())
+	}
+	enumId, err := d.ReadVariant()
+	if err != nil {
+		return err
+	}
+
+	if int(enumId) >= len(p.variants) {
+		return fmt.Errorf("enum field %d is out of range", enumId)
+	}
+
+	f := p.variants[enumId]
+	if f == nil {
+		return fmt.Errorf("enum field %d is unexported or ignored", enumId)
+	}
+
+	return d.skipValue(f.plan)
+}
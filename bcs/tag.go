@@ -1,35 +1,46 @@
 package bcs
 
-import (
-	"fmt"
-	"strings"
-)
+import "github.com/fardream/go-bcs/internal/bcstag"
 
-const tagName = "bcs"
+const tagName = bcstag.Name
 
 type tagValue int64
 
 const (
 	tagValue_Optional tagValue = 1 << iota // optional
 	tagValue_Ignore                        // -
+	tagValue_NoCopy                        // nocopy
 )
 
-func parseTagValue(tag string) (tagValue, error) {
-	var r tagValue
-	tagSegs := strings.Split(tag, ",")
-	for _, seg := range tagSegs {
-		seg := strings.TrimSpace(seg)
-		if seg == "" {
-			continue
-		}
-		switch seg {
-		case "optional":
-			r |= tagValue_Optional
-		case "-":
-			return tagValue_Ignore, nil
-		default:
-			return 0, fmt.Errorf("unknown tag: %s in %s", seg, tag)
-		}
+// fieldTag is the parsed tag of a field.
+type fieldTag struct {
+	flags tagValue
+	// constraints are the constraints on the value, nil if there are none.
+	constraints *constraints
+	// since and until are the first and the last schema versions the field is in, 0 if not set.
+	since int
+	until int
+}
+
+// parseTag parses the tag of a field, see [bcstag.Parse].
+func parseTag(tag string) (fieldTag, error) {
+	t, err := bcstag.Parse(tag)
+	if err != nil {
+		return fieldTag{}, err
+	}
+
+	r := fieldTag{since: t.Since, until: t.Until}
+	if t.Optional {
+		r.flags |= tagValue_Optional
+	}
+	if t.Ignore {
+		r.flags |= tagValue_Ignore
+	}
+	if t.NoCopy {
+		r.flags |= tagValue_NoCopy
+	}
+	if t.HasConstraints() {
+		r.constraints = &constraints{maxLen: t.MaxLen, min: t.Min, max: t.Max, utf8: t.UTF8, ascii: t.ASCII}
 	}
 
 	return r, nil
@@ -42,3 +53,7 @@ func (t tagValue) isOptional() bool {
 func (t tagValue) isIgnored() bool {
 	return t&tagValue_Ignore != 0
 }
+
+func (t tagValue) isNoCopy() bool {
+	return t&tagValue_NoCopy != 0
+}
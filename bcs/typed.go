@@ -0,0 +1,59 @@
+package bcs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"reflect"
+)
+
+// UnmarshalAs decodes a value of type T from data, and errors if data is not completely consumed, like [UnmarshalAll].
+func UnmarshalAs[T any](data []byte) (T, error) {
+	var v T
+	err := UnmarshalAll(data, &v)
+	return v, err
+}
+
+// MustUnmarshal is like [UnmarshalAs], but panics if there is an error.
+func MustUnmarshal[T any](data []byte) T {
+	v, err := UnmarshalAs[T](data)
+	if err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+// Decode decodes a value of type T from the decoder, the same as [Decoder.Decode] with a pointer to T.
+func Decode[T any](d *Decoder) (T, error) {
+	var v T
+	err := d.decodeValue(reflect.ValueOf(&v).Elem(), planFor(reflect.TypeFor[T]()))
+	return v, err
+}
+
+// DecodeSeq returns the sequence of values of type T encoded back to back in r, such as a log of records.
+//
+// The sequence ends when r reaches [io.EOF] at the boundary between two values. If r ends in the middle
+// of a value, or the value cannot be decoded, the error is yielded and the sequence ends.
+func DecodeSeq[T any](r io.Reader) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		d := NewDecoder(r)
+		for {
+			start := d.Offset()
+			v, err := Decode[T](d)
+			switch {
+			case errors.Is(err, io.EOF) && d.Offset() == start:
+				return
+			case errors.Is(err, io.EOF):
+				err = io.ErrUnexpectedEOF
+			case err == nil && d.Offset() == start:
+				// a value taking no bytes would repeat forever.
+				err = fmt.Errorf("cannot decode a sequence of %s, which is encoded as no bytes", reflect.TypeFor[T]().String())
+			}
+			if !yield(v, err) || err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package bcs_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+func TestUnmarshalAs(t *testing.T) {
+	v := Tree{Value: 3, Siblings: []Tree{{Value: 4}}}
+	b := bcs.MustMarshal(v)
+
+	decoded, err := bcs.UnmarshalAs[Tree](b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bcs.MustMarshal(decoded), b) {
+		t.Fatalf("decoded value doesn't round trip")
+	}
+
+	if _, err := bcs.UnmarshalAs[Tree](append(b, 0)); err == nil {
+		t.Fatal("want error for trailing bytes")
+	}
+
+	if p := bcs.MustUnmarshal[*uint16](b[:2]); *p != 3 {
+		t.Fatalf("want 3, got %d", *p)
+	}
+}
+
+func TestDecodeSeq(t *testing.T) {
+	var buf bytes.Buffer
+	e := bcs.NewEncoder(&buf)
+	for i := uint16(0); i < 3; i++ {
+		if err := e.Encode(Tree{Value: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var values []uint16
+	for v, err := range bcs.DecodeSeq[Tree](bytes.NewReader(buf.Bytes())) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, v.Value)
+	}
+	if len(values) != 3 || values[2] != 2 {
+		t.Fatalf("unexpected values: %v", values)
+	}
+
+	// the last value is truncated.
+	var errs []error
+	for _, err := range bcs.DecodeSeq[Tree](bytes.NewReader(buf.Bytes()[:buf.Len()-1])) {
+		errs = append(errs, err)
+	}
+	if len(errs) != 3 || !errors.Is(errs[2], io.ErrUnexpectedEOF) {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	for _, err := range bcs.DecodeSeq[struct{}](bytes.NewReader(buf.Bytes())) {
+		if err == nil {
+			t.Fatal("want error for values taking no bytes")
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	d := bcs.NewBytesDecoder([]byte{1, 0, 2, 0, 0, 0})
+	a, err := bcs.Decode[uint16](d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := bcs.Decode[uint32](d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != 1 || b != 2 || d.Offset() != 6 {
+		t.Fatalf("unexpected values: %d %d, offset %d", a, b, d.Offset())
+	}
+}
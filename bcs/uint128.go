@@ -19,6 +19,8 @@ var (
 	_ json.Unmarshaler = (*Uint128)(nil)
 	_ Marshaler        = (*Uint128)(nil)
 	_ Unmarshaler      = (*Uint128)(nil)
+	_ MarshalerTo      = (*Uint128)(nil)
+	_ UnmarshalerFrom  = (*Uint128)(nil)
 )
 
 func (i Uint128) Big() *big.Int {
@@ -138,6 +140,21 @@ func (i *Uint128) UnmarshalBCS(r io.Reader) (int, error) {
 	return n, nil
 }
 
+func (i Uint128) MarshalBCSTo(e *Encoder) error {
+	return e.WriteU128(i)
+}
+
+func (i *Uint128) UnmarshalBCSFrom(d *Decoder) error {
+	v, err := d.ReadU128()
+	if err != nil {
+		return err
+	}
+
+	*i = v
+
+	return nil
+}
+
 func (i *Uint128) Cmp(j *Uint128) int {
 	switch {
 	case i.hi > j.hi || (i.hi == j.hi && i.lo > j.lo):
@@ -47,11 +47,12 @@ func ULEB128Decode[T ULEB128SupportedTypes](r io.Reader) (T, int, error) {
 	var v, shift T
 	var n int
 	for n < MaxUleb128Length {
-		i, err := r.Read(buf)
-		if i == 0 {
-			return 0, n, fmt.Errorf("zero read in. possible EOF")
-		}
+		i, err := io.ReadFull(r, buf)
 		if err != nil {
+			// the input ends in the middle of the integer.
+			if err == io.EOF && n > 0 {
+				err = io.ErrUnexpectedEOF
+			}
 			return 0, n, err
 		}
 		n += i
@@ -10,3 +10,12 @@ import "io"
 type Unmarshaler interface {
 	UnmarshalBCS(io.Reader) (int, error)
 }
+
+// UnmarshalerFrom customizes the unmarshalling behavior for a type by reading directly from the [Decoder].
+//
+// The decoder keeps track of the bytes consumed, so there is no need to report the number of bytes read.
+// Nested values decoded with [Decoder.Decode] share the depth limit of the decoder.
+// If a type implements both [UnmarshalerFrom] and [Unmarshaler], [UnmarshalerFrom] is used.
+type UnmarshalerFrom interface {
+	UnmarshalBCSFrom(*Decoder) error
+}
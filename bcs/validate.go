@@ -0,0 +1,118 @@
+package bcs
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// BCSValidator checks the invariants of a decoded value. ValidateBCS is called after the value
+// and all its children are decoded, so the children are validated before their parents.
+//
+// The error is returned as a [*ValidationError] with the path to the value.
+type BCSValidator interface {
+	ValidateBCS() error
+}
+
+// BCSPreparer prepares a value for encoding, for example, to check its invariants or fill the derived fields.
+// PrepareBCS is called before the value and its children are encoded, so the parents are prepared before their children.
+// Like the marshalers, pointer receivers are always used, on a copy of the value if it is not addressable,
+// in which case the changes made by PrepareBCS are encoded but not kept in the value.
+//
+// The error is returned as a [*ValidationError] with the path to the value.
+type BCSPreparer interface {
+	PrepareBCS() error
+}
+
+var (
+	validatorType = reflect.TypeFor[BCSValidator]()
+	preparerType  = reflect.TypeFor[BCSPreparer]()
+)
+
+// ValidationError is the error returned by [BCSValidator] or [BCSPreparer], with the path to the value.
+type ValidationError struct {
+	// Path is the path to the value from the top level value, such as "Inputs[2].Amount",
+	// or empty if it is the top level value.
+	Path string
+	Err  error
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("validation failed: %v", e.Err)
+	}
+
+	return fmt.Sprintf("validation failed at %s: %v", e.Path, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// validate calls [BCSValidator] of the decoded value.
+func validate(v reflect.Value) error {
+	if !v.CanAddr() {
+		return nil
+	}
+	if err := v.Addr().Interface().(BCSValidator).ValidateBCS(); err != nil {
+		return &ValidationError{Err: err}
+	}
+
+	return nil
+}
+
+// prepare calls [BCSPreparer] of the value before it is encoded. The value must be addressable
+// if the method has a pointer receiver, see [Encoder.encodeValue].
+func prepare(v reflect.Value, p *typePlan) error {
+	var preparer BCSPreparer
+	switch {
+	case p.preparer:
+		preparer = v.Interface().(BCSPreparer)
+	case p.preparerAddr:
+		preparer = v.Addr().Interface().(BCSPreparer)
+	default:
+		return nil
+	}
+
+	if err := preparer.PrepareBCS(); err != nil {
+		return &ValidationError{Err: err}
+	}
+
+	return nil
+}
+
+// WithField adds the field name to the path of the [*ValidationError] in err, which may be wrapped, and returns err.
+// Other errors are returned as is. Customized marshalers and unmarshalers, such as the code generated by bcsgen,
+// use it to report the same paths as [Marshal] and [Unmarshal].
+func WithField(err error, name string) error {
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		return err
+	}
+
+	if ve.Path == "" || ve.Path[0] == '[' {
+		ve.Path = name + ve.Path
+	} else {
+		ve.Path = name + "." + ve.Path
+	}
+
+	return err
+}
+
+// WithIndex adds the index of a slice or an array to the path of the [*ValidationError] in err, see [WithField].
+func WithIndex(err error, i int) error {
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		return err
+	}
+
+	index := "[" + strconv.Itoa(i) + "]"
+	if ve.Path == "" || ve.Path[0] == '[' {
+		ve.Path = index + ve.Path
+	} else {
+		ve.Path = index + "." + ve.Path
+	}
+
+	return err
+}
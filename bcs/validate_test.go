@@ -0,0 +1,132 @@
+package bcs_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+type Amount struct {
+	Value uint64
+}
+
+func (a *Amount) ValidateBCS() error {
+	if a.Value == 0 {
+		return fmt.Errorf("amount is zero")
+	}
+
+	return nil
+}
+
+type Order struct {
+	Items  []Amount
+	Counts []uint8
+	// Total is filled by PrepareBCS.
+	Total uint64
+	Fee   *Amount `bcs:"optional"`
+}
+
+func (o *Order) PrepareBCS() error {
+	if len(o.Counts) != len(o.Items) {
+		return fmt.Errorf("%d counts for %d items", len(o.Counts), len(o.Items))
+	}
+	o.Total = 0
+	for i, item := range o.Items {
+		o.Total += item.Value * uint64(o.Counts[i])
+	}
+
+	return nil
+}
+
+func (o *Order) ValidateBCS() error {
+	if len(o.Counts) != len(o.Items) {
+		return fmt.Errorf("%d counts for %d items", len(o.Counts), len(o.Items))
+	}
+
+	return nil
+}
+
+type Orders struct {
+	Orders []Order
+}
+
+func TestValidateBCS(t *testing.T) {
+	v := Orders{Orders: []Order{{Items: []Amount{{1}}, Counts: []uint8{2}}, {Items: []Amount{{3}, {4}}, Counts: []uint8{1, 2}}}}
+	b, err := bcs.Marshal(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Orders[0].Total != 2 || v.Orders[1].Total != 11 {
+		t.Fatalf("want the totals prepared, got %d and %d", v.Orders[0].Total, v.Orders[1].Total)
+	}
+
+	decoded, err := bcs.UnmarshalAs[Orders](b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Orders[1].Total != 11 {
+		t.Fatalf("want total 11, got %d", decoded.Orders[1].Total)
+	}
+
+	v.Orders[1].Items[1].Value = 0
+	v.Orders[0].Fee = &Amount{}
+	b = bcs.MustMarshal(&v)
+
+	_, err = bcs.UnmarshalAs[Orders](b)
+	var ve *bcs.ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("want validation error, got %v", err)
+	}
+	if ve.Path != "Orders[0].Fee" {
+		t.Fatalf("want path Orders[0].Fee, got %s", ve.Path)
+	}
+
+	v.Orders[0].Fee = nil
+	b = bcs.MustMarshal(&v)
+	_, err = bcs.UnmarshalAs[Orders](b)
+	if !errors.As(err, &ve) || ve.Path != "Orders[1].Items[1]" {
+		t.Fatalf("want validation error at Orders[1].Items[1], got %v", err)
+	}
+}
+
+func TestPrepareBCS(t *testing.T) {
+	v := Orders{Orders: []Order{{}, {Items: []Amount{{3}}}}}
+	_, err := bcs.Marshal(&v)
+	var ve *bcs.ValidationError
+	if !errors.As(err, &ve) || ve.Path != "Orders[1]" {
+		t.Fatalf("want validation error at Orders[1], got %v", err)
+	}
+	if err.Error() != "validation failed at Orders[1]: 0 counts for 1 items" {
+		t.Fatalf("unexpected error message: %v", err)
+	}
+
+	o := Order{Items: []Amount{{3}}}
+	if _, err := bcs.Marshal(&o); !errors.As(err, &ve) || ve.Path != "" {
+		t.Fatalf("want validation error at the top level value, got %v", err)
+	}
+}
+
+// wrappedAmount wraps the errors of decoding an [Amount], including its validation error.
+type wrappedAmount struct {
+	Amount Amount
+}
+
+func (w *wrappedAmount) UnmarshalBCSFrom(d *bcs.Decoder) error {
+	if _, err := d.Decode(&w.Amount); err != nil {
+		return fmt.Errorf("decode wrapped amount: %w", err)
+	}
+
+	return nil
+}
+
+func TestValidateBCS_wrapped(t *testing.T) {
+	b := bcs.MustMarshal(&struct{ Amounts []Amount }{Amounts: []Amount{{1}, {0}}})
+
+	_, err := bcs.UnmarshalAs[struct{ Amounts []wrappedAmount }](b)
+	var ve *bcs.ValidationError
+	if !errors.As(err, &ve) || ve.Path != "Amounts[1]" {
+		t.Fatalf("want validation error at Amounts[1], got %v", err)
+	}
+}
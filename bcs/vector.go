@@ -0,0 +1,96 @@
+package bcs
+
+import (
+	"fmt"
+	"iter"
+)
+
+// vectorFrame is a vector started by [Encoder.BeginVector].
+type vectorFrame struct {
+	expected int
+	count    int
+	// encoding is the nesting of encode calls when the vector is started, calls at the same nesting are the elements.
+	encoding int
+}
+
+// BeginVector starts a vector of n elements by writing its length, so the elements can be encoded one at a time
+// with [Encoder.Encode] instead of from a slice held in memory. Each call to [Encoder.Encode] until [Encoder.EndVector]
+// writes one element, and a nested vector started with BeginVector counts as one element of the outer vector.
+//
+// The output is flushed after each element, so a vector of any size can be written in constant memory.
+// Only the values written with [Encoder.Encode], the codecs of this package, and the codecs created by [CodecOf]
+// are counted as elements, while the values they contain are not. The primitive writers such as [Encoder.WriteU64]
+// are not counted, and a [Codec] implemented outside of this package counts once for each value it writes with
+// other codecs, unless it is created by [CodecOf].
+func (e *Encoder) BeginVector(n int) error {
+	if err := e.countElement(); err != nil {
+		return err
+	}
+	if err := e.WriteLength(n); err != nil {
+		return err
+	}
+
+	e.vectors = append(e.vectors, vectorFrame{expected: n, encoding: e.encoding})
+
+	return nil
+}
+
+// EndVector ends the vector started by the last call to [Encoder.BeginVector], and errors if
+// the number of elements written is not the length of the vector, including when extra elements were rejected.
+func (e *Encoder) EndVector() error {
+	if len(e.vectors) == 0 {
+		return fmt.Errorf("EndVector is called without BeginVector")
+	}
+
+	f := e.vectors[len(e.vectors)-1]
+	e.vectors = e.vectors[:len(e.vectors)-1]
+	if f.count != f.expected {
+		return fmt.Errorf("vector expects %d elements, but got %d", f.expected, f.count)
+	}
+
+	return nil
+}
+
+// countElement counts an element of the current vector if the value is encoded directly into the vector,
+// and errors if the vector already has all of its elements. Rejected elements are counted as well,
+// so [Encoder.EndVector] reports the vector is incomplete.
+func (e *Encoder) countElement() error {
+	if len(e.vectors) == 0 {
+		return nil
+	}
+
+	f := &e.vectors[len(e.vectors)-1]
+	if f.encoding != e.encoding {
+		return nil
+	}
+	f.count++
+	if f.count > f.expected {
+		return fmt.Errorf("vector expects %d elements, cannot write more", f.expected)
+	}
+
+	return nil
+}
+
+// VectorIter reads the length of a vector from the decoder, and yields its elements of type T one at a time,
+// so a vector of any size can be read in constant memory. Go methods cannot have type parameters, so this
+// is a function of the [Decoder] instead of a method.
+//
+// The length is read when the iteration starts. If an element cannot be decoded, the error is yielded and the
+// iteration ends. If the iteration is stopped early, the rest of the vector is left unread in the decoder.
+func VectorIter[T any](d *Decoder) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		n, err := d.ReadLength()
+		if err != nil {
+			var zero T
+			yield(zero, err)
+			return
+		}
+
+		for i := 0; i < n; i++ {
+			v, err := Decode[T](d)
+			if !yield(v, err) || err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package bcs_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+func TestBeginVector(t *testing.T) {
+	want := bcs.MustMarshal([][]uint32{{1, 2}, {}, {3}})
+
+	var buf bytes.Buffer
+	e := bcs.NewEncoder(&buf)
+	vectors := [][]uint32{{1, 2}, {}, {3}}
+	if err := e.BeginVector(len(vectors)); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range vectors {
+		if err := e.BeginVector(len(v)); err != nil {
+			t.Fatal(err)
+		}
+		for _, x := range v {
+			if err := e.Encode(x); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := e.EndVector(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.EndVector(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("want: %v\ngot:  %v", want, buf.Bytes())
+	}
+}
+
+func TestBeginVector_count(t *testing.T) {
+	e := bcs.NewEncoder(&bytes.Buffer{})
+	if err := e.BeginVector(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.EndVector(); err == nil {
+		t.Fatal("want error for missing element")
+	}
+
+	if err := e.BeginVector(1); err != nil {
+		t.Fatal(err)
+	}
+	// the nested values of the struct are not elements of the vector.
+	if err := e.Encode(Tree{Value: 1, Siblings: []Tree{{}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Encode(Tree{}); err == nil {
+		t.Fatal("want error for extra element")
+	}
+	if err := e.EndVector(); err == nil {
+		t.Fatal("want error for rejected extra element")
+	}
+
+	if err := e.EndVector(); err == nil {
+		t.Fatal("want error for EndVector without BeginVector")
+	}
+}
+
+func TestVectorIter(t *testing.T) {
+	v := []Tree{{Value: 1}, {Value: 2, Siblings: []Tree{{Value: 3}}}, {Value: 4}}
+	b := append(bcs.MustMarshal(v), 0xff)
+
+	d := bcs.NewDecoder(bytes.NewReader(b))
+	var got []Tree
+	for tree, err := range bcs.VectorIter[Tree](d) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, tree)
+	}
+	if !bytes.Equal(bcs.MustMarshal(got), b[:len(b)-1]) {
+		t.Fatalf("decoded vector doesn't round trip: %v", got)
+	}
+	if x, err := bcs.Decode[uint8](d); err != nil || x != 0xff {
+		t.Fatalf("want the byte after the vector, got %d, %v", x, err)
+	}
+
+	d = bcs.NewDecoder(bytes.NewReader(b[:len(b)-3]))
+	var n int
+	var lastErr error
+	for _, err := range bcs.VectorIter[Tree](d) {
+		n++
+		lastErr = err
+	}
+	if n != 3 || lastErr == nil {
+		t.Fatalf("want error at the last element, got %d elements and %v", n, lastErr)
+	}
+}
+
+// pairCodec is composed of other codecs, and is one element of a vector.
+var pairCodec = bcs.CodecOf(
+	func(e *bcs.Encoder, v [2]uint8) error {
+		if err := bcs.U8.Encode(e, v[0]); err != nil {
+			return err
+		}
+		return bcs.U8.Encode(e, v[1])
+	},
+	func(d *bcs.Decoder) ([2]uint8, error) {
+		a, err := bcs.U8.Decode(d)
+		if err != nil {
+			return [2]uint8{}, err
+		}
+		b, err := bcs.U8.Decode(d)
+		return [2]uint8{a, b}, err
+	},
+)
+
+func TestBeginVector_codecOf(t *testing.T) {
+	var buf bytes.Buffer
+	e := bcs.NewEncoder(&buf)
+	if err := e.BeginVector(2); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range [][2]uint8{{1, 2}, {3, 4}} {
+		if err := pairCodec.Encode(e, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.EndVector(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{2, 1, 2, 3, 4}; !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("want: %v\ngot:  %v", want, buf.Bytes())
+	}
+}
@@ -0,0 +1,42 @@
+package bcs
+
+import "github.com/fardream/go-bcs/internal/bcstag"
+
+// LatestVersion is the default schema version of [Encoder] and [Decoder], which includes the fields
+// tagged with `since` and excludes the fields tagged with `until`.
+const LatestVersion = bcstag.LatestVersion
+
+// inVersion checks if the field is in the schema version.
+//
+// Fields tagged `since=n` are added in version n, and fields tagged `until=n` are removed after version n.
+// Both bounds are inclusive.
+func (f *fieldPlan) inVersion(version int) bool {
+	return bcstag.InVersion(version, f.since, f.until)
+}
+
+// isVersioned checks if the field is tagged with `since` or `until`.
+func (f *fieldPlan) isVersioned() bool {
+	return f.since != 0 || f.until != 0
+}
+
+// SetVersion sets the schema version of the encoded values, see [Marshal] for the tags selecting the fields of a version.
+// The default is [LatestVersion].
+func (e *Encoder) SetVersion(version int) {
+	e.version = version
+}
+
+// Version returns the schema version of the encoder, see [Encoder.SetVersion].
+func (e *Encoder) Version() int {
+	return e.version
+}
+
+// SetVersion sets the schema version of the decoded values, see [Marshal] for the tags selecting the fields of a version.
+// The fields not in the version are not read, and are set to zero. The default is [LatestVersion].
+func (d *Decoder) SetVersion(version int) {
+	d.version = version
+}
+
+// Version returns the schema version of the decoder, see [Decoder.SetVersion].
+func (d *Decoder) Version() int {
+	return d.version
+}
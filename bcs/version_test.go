@@ -0,0 +1,82 @@
+package bcs_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+type Account struct {
+	Owner    uint8
+	Balance  uint64   `bcs:"until=1"`
+	Coins    []uint64 `bcs:"since=2"`
+	Frozen   bool     `bcs:"since=3"`
+	Nickname *string  `bcs:"optional,since=2,until=3"`
+}
+
+func TestVersion(t *testing.T) {
+	name := "a"
+	v := Account{Owner: 1, Balance: 2, Coins: []uint64{3}, Frozen: true, Nickname: &name}
+	for _, c := range []struct {
+		version int
+		want    []byte
+	}{
+		{1, []byte{1, 2, 0, 0, 0, 0, 0, 0, 0}},
+		{2, []byte{1, 1, 3, 0, 0, 0, 0, 0, 0, 0, 1, 1, 'a'}},
+		{3, []byte{1, 1, 3, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 'a'}},
+		{bcs.LatestVersion, []byte{1, 1, 3, 0, 0, 0, 0, 0, 0, 0, 1}},
+		{4, []byte{1, 1, 3, 0, 0, 0, 0, 0, 0, 0, 1}},
+	} {
+		var buf bytes.Buffer
+		e := bcs.NewEncoder(&buf)
+		e.SetVersion(c.version)
+		if err := e.Encode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), c.want) {
+			t.Fatalf("version %d, want: %v\ngot:  %v", c.version, c.want, buf.Bytes())
+		}
+
+		// the fields not in the version are reset.
+		decoded := v
+		d := bcs.NewBytesDecoder(c.want)
+		d.SetVersion(c.version)
+		if _, err := d.Decode(&decoded); err != nil {
+			t.Fatal(err)
+		}
+		var reencoded bytes.Buffer
+		e = bcs.NewEncoder(&reencoded)
+		if err := e.Encode(&decoded); err != nil {
+			t.Fatal(err)
+		}
+		want := bcs.MustMarshal(&Account{Owner: 1, Coins: decoded.Coins, Frozen: decoded.Frozen})
+		if c.version >= 2 && !bytes.Equal(reencoded.Bytes(), want) {
+			t.Fatalf("version %d, decoded value doesn't round trip: %v", c.version, decoded)
+		}
+		if c.version == 1 && (decoded.Balance != 2 || decoded.Coins != nil || decoded.Nickname != nil) {
+			t.Fatalf("version 1, unexpected decoded value: %v", decoded)
+		}
+
+		d = bcs.NewBytesDecoder(c.want)
+		d.SetVersion(c.version)
+		if err := bcs.Skip[Account](d); err != nil || d.Offset() != len(c.want) {
+			t.Fatalf("version %d, failed to skip: %d, %v", c.version, d.Offset(), err)
+		}
+	}
+}
+
+func TestVersion_invalidTag(t *testing.T) {
+	for _, v := range []any{
+		&struct {
+			A uint8 `bcs:"since=0"`
+		}{},
+		&struct {
+			A uint8 `bcs:"since=3,until=2"`
+		}{},
+	} {
+		if _, err := bcs.Marshal(v); err == nil {
+			t.Fatalf("want error for %T", v)
+		}
+	}
+}
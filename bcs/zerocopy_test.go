@@ -0,0 +1,80 @@
+package bcs_test
+
+import (
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+type NoCopyStruct struct {
+	Copied  []byte
+	Aliased []byte `bcs:"nocopy"`
+	Label   string `bcs:"nocopy"`
+}
+
+func TestBytesDecoder_zeroCopy(t *testing.T) {
+	data := bcs.MustMarshal(&MyStruct{Boolean: true, Bytes: []byte{1, 2, 3}, Label: "abc"})
+
+	var copied MyStruct
+	if _, err := bcs.Unmarshal(data, &copied); err != nil {
+		t.Fatal(err)
+	}
+
+	var aliased MyStruct
+	d := bcs.NewBytesDecoder(data)
+	d.SetZeroCopy(true)
+	if _, err := d.Decode(&aliased); err != nil {
+		t.Fatal(err)
+	}
+
+	data[3] = 9
+	data[len(data)-1] = 'd'
+
+	if !slices.Equal(copied.Bytes, []byte{1, 2, 3}) || copied.Label != "abc" {
+		t.Fatalf("copied values should not change: %v", copied)
+	}
+	if !slices.Equal(aliased.Bytes, []byte{1, 9, 3}) || aliased.Label != "abd" {
+		t.Fatalf("aliased values should change with input: %v", aliased)
+	}
+
+	// appending to the aliased bytes doesn't overwrite the input.
+	_ = append(aliased.Bytes, 100)
+	if data[5] != 3 {
+		t.Fatalf("input is overwritten: %v", data)
+	}
+}
+
+func TestBytesDecoder_noCopyTag(t *testing.T) {
+	data := bcs.MustMarshal(&NoCopyStruct{Copied: []byte{1}, Aliased: []byte{2}, Label: "a"})
+
+	var v NoCopyStruct
+	if err := bcs.UnmarshalAll(data, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	data[1] = 10
+	data[3] = 20
+	data[5] = 'b'
+	if v.Copied[0] != 1 || v.Aliased[0] != 20 || v.Label != "b" {
+		t.Fatalf("unexpected values: %v", v)
+	}
+}
+
+func TestBytesDecoder_truncated(t *testing.T) {
+	data := bcs.MustMarshal(&MyStruct{Boolean: true, Bytes: []byte{1, 2, 3}, Label: "abc"})
+	for i := 0; i < len(data); i++ {
+		var v MyStruct
+		_, err := bcs.Unmarshal(data[:i], &v)
+		if err != io.EOF && err != io.ErrUnexpectedEOF {
+			t.Fatalf("truncated at %d: want EOF, got %v", i, err)
+		}
+	}
+
+	// declared length larger than the input fails without allocation.
+	var b []byte
+	if _, err := bcs.Unmarshal([]byte{0xff, 0xff, 0xff, 0xff, 0x07, 1}, &b); err != io.ErrUnexpectedEOF {
+		t.Fatalf("want unexpected EOF, got %v", err)
+	}
+}
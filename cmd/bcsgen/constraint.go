@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"go/types"
+	"strconv"
+
+	"github.com/fardream/go-bcs/internal/bcstag"
+)
+
+// constraints are the checks on the value of a field declared in its tag, the same as the bcs package.
+type constraints struct {
+	// maxLen is the max length of a vector or string, -1 if not set.
+	maxLen int
+	// min and max are the bounds of integers, which default to the bounds of the type, see [constraints.compile].
+	// hasMin and hasMax indicate the bounds are set in the tag.
+	min, max string
+	hasMin   bool
+	hasMax   bool
+	utf8     bool
+	ascii    bool
+}
+
+func (c constraints) isSet() bool {
+	return c.maxLen >= 0 || c.hasMin || c.hasMax || c.utf8 || c.ascii
+}
+
+// compile checks the constraints can be applied to a field of type t, and fills the default bounds of integers.
+func (c *constraints) compile(t types.Type, b *bcsTypes) error {
+	t = deref(t)
+
+	_, isSlice := t.Underlying().(*types.Slice)
+	isString := isBasic(t, types.IsString)
+	isBytes := isSlice && isByte(t.Underlying().(*types.Slice).Elem())
+	if c.maxLen >= 0 && (!isString && !isSlice || implements(t, b.unmarshalerFrom) || implements(t, b.unmarshaler) || b.isEnum(t)) {
+		return fmt.Errorf("tag maxlen can only be used on vectors and strings, got %s", t.String())
+	}
+	if (c.utf8 || c.ascii) && !isString && !isBytes {
+		return fmt.Errorf("tag utf8 and ascii can only be used on strings and []byte, got %s", t.String())
+	}
+	if !c.hasMin && !c.hasMax {
+		return nil
+	}
+
+	basic, ok := t.Underlying().(*types.Basic)
+	if !ok || basic.Info()&types.IsInteger == 0 || basic.Kind() == types.Int || basic.Kind() == types.Uint || basic.Kind() == types.Uintptr {
+		return fmt.Errorf("tag min and max can only be used on integers, got %s", t.String())
+	}
+	bits := int(sizes.Sizeof(basic)) * 8
+	if basic.Info()&types.IsUnsigned != 0 {
+		lo, hi, err := bcstag.UintBounds(c.min, c.max, bits)
+		if err != nil {
+			return fmt.Errorf("%w for %s", err, t.String())
+		}
+		c.min, c.max = strconv.FormatUint(lo, 10), strconv.FormatUint(hi, 10)
+	} else {
+		lo, hi, err := bcstag.IntBounds(c.min, c.max, bits)
+		if err != nil {
+			return fmt.Errorf("%w for %s", err, t.String())
+		}
+		c.min, c.max = strconv.FormatInt(lo, 10), strconv.FormatInt(hi, 10)
+	}
+
+	return nil
+}
+
+// deref returns the type t points to, through any number of pointers.
+func deref(t types.Type) types.Type {
+	for {
+		ptr, ok := t.Underlying().(*types.Pointer)
+		if !ok {
+			return t
+		}
+		t = ptr.Elem()
+	}
+}
+
+// isBasic checks if the underlying type of t is a basic type with the info.
+func isBasic(t types.Type, info types.BasicInfo) bool {
+	b, ok := t.Underlying().(*types.Basic)
+	return ok && b.Info()&info != 0
+}
+
+// checkConstraints writes the code checking the constraints on x of type t, which is the field being generated.
+// Nil pointers are not checked.
+func (g *generator) checkConstraints(x string, t types.Type, c constraints) {
+	if !c.isSet() {
+		return
+	}
+
+	opened := 0
+	for {
+		ptr, ok := t.Underlying().(*types.Pointer)
+		if !ok {
+			break
+		}
+		g.p("if %s != nil {", x)
+		x, t = "*"+x, ptr.Elem()
+		opened++
+	}
+
+	if c.maxLen >= 0 {
+		g.p("if len(%s) > %d {", x, c.maxLen)
+		g.fail("\"length %%d exceeds maxlen %d\", len(%s)", c.maxLen, x)
+		g.p("}")
+	}
+	if c.hasMin || c.hasMax {
+		var conds []string
+		if c.hasMin {
+			conds = append(conds, fmt.Sprintf("%s < %s", x, c.min))
+		}
+		if c.hasMax {
+			conds = append(conds, fmt.Sprintf("%s > %s", x, c.max))
+		}
+		if len(conds) == 2 {
+			g.p("if %s || %s {", conds[0], conds[1])
+		} else {
+			g.p("if %s {", conds[0])
+		}
+		g.fail("\"%%d is out of range [%s, %s]\", %s", c.min, c.max, x)
+		g.p("}")
+	}
+
+	isString := isBasic(t, types.IsString)
+	switch {
+	case c.ascii:
+		g.imports["unicode/utf8"] = "utf8"
+		g.p("for _, c := range []byte(%s) {", x)
+		g.p("if c >= utf8.RuneSelf {")
+		g.fail("\"not ascii\"")
+		g.p("}")
+		g.p("}")
+	case c.utf8 && isString:
+		g.imports["unicode/utf8"] = "utf8"
+		g.p("if !utf8.ValidString(%s) {", conv("string", x, t))
+		g.fail("\"not valid utf8\"")
+		g.p("}")
+	case c.utf8:
+		g.imports["unicode/utf8"] = "utf8"
+		g.p("if !utf8.Valid(%s) {", conv("[]byte", x, t))
+		g.fail("\"not valid utf8\"")
+		g.p("}")
+	}
+
+	for ; opened > 0; opened-- {
+		g.p("}")
+	}
+}
+
+// fail writes the code returning the error of the constraint on the field being generated, the same as the bcs package.
+func (g *generator) fail(format string, args ...any) {
+	g.imports["fmt"] = "fmt"
+	g.p("return %s", g.wrap(fmt.Sprintf("&bcs.ValidationError{Err: fmt.Errorf(%s)}", fmt.Sprintf(format, args...))))
+}
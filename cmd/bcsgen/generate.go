@@ -0,0 +1,1030 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/types"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/fardream/go-bcs/internal/bcstag"
+)
+
+const bcsPath = "github.com/fardream/go-bcs/bcs"
+
+// maxPrealloc is the max bytes of memory preallocated for a decoded slice,
+// the same as the reflection based decoder.
+const maxPrealloc = 1024 * 1024
+
+// sizes computes the memory size of element types to bound the preallocation.
+// It is fixed so the generated code doesn't depend on the platform bcsgen runs on.
+var sizes = types.SizesFor("gc", "amd64")
+
+// target is a type to generate methods for.
+type target struct {
+	name   string
+	named  *types.Named
+	st     *types.Struct
+	isEnum bool
+	fields []field
+}
+
+// field is an exported and not ignored field of a target.
+type field struct {
+	index    int
+	name     string
+	typ      types.Type
+	optional bool
+	nocopy   bool
+	// constraints are checked before the field is encoded and after it is decoded.
+	constraints constraints
+	// since and until are the first and the last schema versions the field is in, 0 if not set.
+	since int
+	until int
+}
+
+// generator writes the code for the targets of a package.
+type generator struct {
+	pkg *types.Package
+	// bcs are the interfaces of the bcs package deciding how the values are encoded and decoded.
+	bcs     *bcsTypes
+	targets map[*types.TypeName]*target
+	// imports are the packages used by the generated code, from path to name.
+	imports map[string]string
+	buf     bytes.Buffer
+	// loops is the number of enclosing loops, used to name the index variables.
+	loops int
+	// fresh indicates the next line starts a new scope, see [generator.open].
+	fresh bool
+	// limit is the maxlen of the vector or string decoded next, checked before it is allocated, -1 if not set.
+	limit int
+	// path are the fields and indices to the value being encoded or decoded, as the formats wrapping
+	// its errors, see [generator.wrap].
+	path []string
+}
+
+// generate generates the methods and the test for the types in the package in dir.
+func generate(dir string, typeNames []string) ([]byte, []byte, error) {
+	pkg, bcsPkg, typeErrors, err := loadPackage(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := newBcsTypes(bcsPkg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	g := &generator{
+		pkg:     pkg,
+		bcs:     b,
+		targets: make(map[*types.TypeName]*target),
+		limit:   -1,
+	}
+
+	var targets []*target
+	for _, name := range typeNames {
+		t, err := g.lookup(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		g.targets[t.named.Obj()] = t
+		targets = append(targets, t)
+	}
+	for _, t := range targets {
+		if err := checkValid(t, typeErrors); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	header := fmt.Sprintf("// Code generated by \"bcsgen -type %s\"; DO NOT EDIT.\n\n", strings.Join(typeNames, ","))
+
+	g.imports = map[string]string{}
+	for _, t := range targets {
+		if err := g.generateMethods(t); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", t.name, err)
+		}
+	}
+	code, err := g.finish(header)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	g.imports = map[string]string{"bytes": "bytes", "math/rand": "rand", "testing": "testing"}
+	for _, t := range targets {
+		g.generateTest(t)
+	}
+	test, err := g.finish(header)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return code, test, nil
+}
+
+// lookup finds the type of name in the package, and parses its fields.
+func (g *generator) lookup(name string) (*target, error) {
+	obj, ok := g.pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("cannot find type %s in package %s", name, g.pkg.Path())
+	}
+	named, ok := obj.Type().(*types.Named)
+	if !ok || obj.IsAlias() {
+		return nil, fmt.Errorf("%s is not a defined type", name)
+	}
+	if named.TypeParams().Len() > 0 {
+		return nil, fmt.Errorf("generic type %s is not supported", name)
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a struct", name)
+	}
+	for _, m := range g.bcs.marshalers() {
+		if implements(named, m.iface) {
+			return nil, fmt.Errorf("%s already implements %s", name, m.name)
+		}
+	}
+	// the generated methods cannot be declared if there are methods of the same names, whatever their signatures.
+	for _, m := range []string{"MarshalBCSTo", "UnmarshalBCSFrom"} {
+		if obj, _, _ := types.LookupFieldOrMethod(named, true, nil, m); obj != nil {
+			return nil, fmt.Errorf("%s already has %s", name, m)
+		}
+	}
+
+	t := &target{
+		name:   name,
+		named:  named,
+		st:     st,
+		isEnum: g.bcs.isEnum(named),
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		if !f.Exported() {
+			continue
+		}
+		tag, err := parseTag(reflect.StructTag(st.Tag(i)).Get(bcstag.Name))
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", name, f.Name(), err)
+		}
+		if tag.ignore {
+			continue
+		}
+		if tag.optional {
+			if _, ok := f.Type().Underlying().(*types.Pointer); !ok {
+				return nil, fmt.Errorf("%s.%s: optional field can only be pointer", name, f.Name())
+			}
+		}
+		if t.isEnum && (tag.since != 0 || tag.until != 0) {
+			return nil, fmt.Errorf("%s.%s: since and until are not supported for enum variants", name, f.Name())
+		}
+		if t.isEnum {
+			switch f.Type().Underlying().(type) {
+			case *types.Pointer, *types.Interface:
+			default:
+				return nil, fmt.Errorf("%s.%s: enum only supports fields that are either pointers or interfaces, unless they are ignored", name, f.Name())
+			}
+		}
+		if err := tag.constraints.compile(f.Type(), g.bcs); err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", name, f.Name(), err)
+		}
+		t.fields = append(t.fields, field{
+			index:       i,
+			name:        f.Name(),
+			typ:         f.Type(),
+			optional:    tag.optional,
+			nocopy:      tag.nocopy,
+			constraints: tag.constraints,
+			since:       tag.since,
+			until:       tag.until,
+		})
+	}
+
+	return t, nil
+}
+
+// checkValid checks the fields of t don't contain types that failed to type check.
+func checkValid(t *target, typeErrors []error) error {
+	for _, f := range t.fields {
+		if strings.Contains(types.TypeString(f.typ, nil), "invalid type") {
+			return fmt.Errorf("%s.%s has invalid type: %w", t.name, f.name, errors.Join(typeErrors...))
+		}
+	}
+
+	return nil
+}
+
+// tag is the parsed bcs tag of a field, see the tag rules of [bcs.Marshal].
+type tag struct {
+	optional    bool
+	ignore      bool
+	nocopy      bool
+	constraints constraints
+	since       int
+	until       int
+}
+
+// parseTag parses the tag of a field, see [bcstag.Parse].
+func parseTag(s string) (tag, error) {
+	t, err := bcstag.Parse(s)
+	if err != nil {
+		return tag{}, err
+	}
+
+	return tag{
+		optional: t.Optional,
+		ignore:   t.Ignore,
+		nocopy:   t.NoCopy,
+		constraints: constraints{
+			maxLen: t.MaxLen,
+			min:    t.Min,
+			max:    t.Max,
+			hasMin: t.Min != "",
+			hasMax: t.Max != "",
+			utf8:   t.UTF8,
+			ascii:  t.ASCII,
+		},
+		since: t.Since,
+		until: t.Until,
+	}, nil
+}
+
+// bcsTypes are the interfaces of the bcs package, which decide how the values of a type are encoded and decoded.
+type bcsTypes struct {
+	marshalerTo     *types.Interface
+	marshaler       *types.Interface
+	unmarshalerFrom *types.Interface
+	unmarshaler     *types.Interface
+	enum            *types.Interface
+	preparer        *types.Interface
+	validator       *types.Interface
+}
+
+// newBcsTypes looks up the interfaces in the bcs package pkg.
+func newBcsTypes(pkg *types.Package) (*bcsTypes, error) {
+	b := &bcsTypes{}
+	for _, i := range []struct {
+		name  string
+		iface **types.Interface
+	}{
+		{"MarshalerTo", &b.marshalerTo},
+		{"Marshaler", &b.marshaler},
+		{"UnmarshalerFrom", &b.unmarshalerFrom},
+		{"Unmarshaler", &b.unmarshaler},
+		{"Enum", &b.enum},
+		{"BCSPreparer", &b.preparer},
+		{"BCSValidator", &b.validator},
+	} {
+		obj, ok := pkg.Scope().Lookup(i.name).(*types.TypeName)
+		if !ok {
+			return nil, fmt.Errorf("cannot find %s in %s", i.name, pkg.Path())
+		}
+		iface, ok := obj.Type().Underlying().(*types.Interface)
+		if !ok {
+			return nil, fmt.Errorf("%s.%s is not an interface", pkg.Path(), i.name)
+		}
+		*i.iface = iface
+	}
+
+	return b, nil
+}
+
+// namedInterface is an interface of the bcs package with its name.
+type namedInterface struct {
+	name  string
+	iface *types.Interface
+}
+
+// marshalers returns the customized marshalers and unmarshalers, named after their methods.
+func (b *bcsTypes) marshalers() []namedInterface {
+	return []namedInterface{
+		{"MarshalBCSTo", b.marshalerTo},
+		{"MarshalBCS", b.marshaler},
+		{"UnmarshalBCSFrom", b.unmarshalerFrom},
+		{"UnmarshalBCS", b.unmarshaler},
+	}
+}
+
+// customized checks if t or *t implements any of the customized marshalers or unmarshalers.
+func (b *bcsTypes) customized(t types.Type) bool {
+	for _, m := range b.marshalers() {
+		if implements(t, m.iface) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isEnum checks if t implements [bcs.Enum], which requires the method in the method set of t itself.
+func (b *bcsTypes) isEnum(t types.Type) bool {
+	return types.Implements(t, b.enum)
+}
+
+// implements checks if t or *t implements iface.
+// All the values the generated code encodes and decodes are addressable.
+func implements(t types.Type, iface *types.Interface) bool {
+	return types.Implements(t, iface) || types.Implements(types.NewPointer(t), iface)
+}
+
+// hasHook checks if the values of t implement the hook iface, [bcs.BCSPreparer] or [bcs.BCSValidator],
+// which are only called by the encoder and decoder for values that are not pointers or interfaces.
+func hasHook(t types.Type, iface *types.Interface) bool {
+	switch t.Underlying().(type) {
+	case *types.Pointer, *types.Interface:
+		return false
+	}
+
+	return implements(t, iface)
+}
+
+// isTarget checks if t is one of the types being generated.
+func (g *generator) isTarget(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	return ok && g.targets[named.Obj()] != nil
+}
+
+// p writes a line of code.
+func (g *generator) p(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+	g.buf.WriteByte('\n')
+	g.fresh = false
+}
+
+// check writes the call, and returns the error from it.
+func (g *generator) check(format string, args ...any) {
+	g.p("if err := %s; err != nil {", fmt.Sprintf(format, args...))
+	g.p("return err")
+	g.p("}")
+}
+
+// checkNested writes the call encoding or decoding a nested value, and returns the error from it
+// with the path to the value, see [generator.wrap].
+func (g *generator) checkNested(format string, args ...any) {
+	g.p("if err := %s; err != nil {", fmt.Sprintf(format, args...))
+	g.p("return %s", g.wrap("err"))
+	g.p("}")
+}
+
+// decodeNested writes the code decoding x with the decoder, and returns the error with the path to the value.
+func (g *generator) decodeNested(x string) {
+	g.p("if _, err := d.Decode(%s); err != nil {", x)
+	g.p("return %s", g.wrap("err"))
+	g.p("}")
+}
+
+// pushField adds the field name to the path of the values encoded or decoded next.
+func (g *generator) pushField(name string) {
+	g.path = append(g.path, fmt.Sprintf("bcs.WithField(%%s, %q)", name))
+}
+
+// pushIndex adds the index variable i to the path of the values encoded or decoded next.
+func (g *generator) pushIndex(i string) {
+	g.path = append(g.path, "bcs.WithIndex(%s, "+i+")")
+}
+
+// pop removes the last field or index from the path.
+func (g *generator) pop() {
+	g.path = g.path[:len(g.path)-1]
+}
+
+// wrap returns the expression adding the path to the value to the [bcs.ValidationError] in err,
+// the same as the reflection based encoder and decoder.
+func (g *generator) wrap(err string) string {
+	for i := len(g.path) - 1; i >= 0; i-- {
+		err = fmt.Sprintf(g.path[i], err)
+	}
+
+	return err
+}
+
+// typeString returns t as written in the generated code, and records the imports it needs.
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string {
+		if p == g.pkg {
+			return ""
+		}
+		g.imports[p.Path()] = p.Name()
+		return p.Name()
+	})
+}
+
+// isType checks if t is the predeclared type of name, or []byte if name is "[]byte".
+func isType(t types.Type, name string) bool {
+	if name == "[]byte" {
+		return types.Identical(t, types.NewSlice(types.Typ[types.Byte]))
+	}
+
+	return types.Identical(t, types.Universe.Lookup(name).Type())
+}
+
+// conv converts x of type t to the basic type, unless t is already the basic type.
+func conv(basic string, x string, t types.Type) string {
+	if isType(t, basic) {
+		return x
+	}
+
+	return fmt.Sprintf("%s(%s)", basic, x)
+}
+
+// operand returns x in parentheses if it is a pointer indirection, so it can be used with selectors and indices.
+func operand(x string) string {
+	if strings.HasPrefix(x, "*") {
+		return "(" + x + ")"
+	}
+
+	return x
+}
+
+// receiver returns x as the receiver of a method call, where the pointer indirection is implicit.
+func receiver(x string) string {
+	return operand(strings.TrimPrefix(x, "*"))
+}
+
+// addr returns the address of x.
+func addr(x string) string {
+	if strings.HasPrefix(x, "*") {
+		return x[1:]
+	}
+
+	return "&" + x
+}
+
+// open starts a block for the variables declared by the following code, unless the code
+// is the start of a new scope, such as the body of a loop.
+func (g *generator) open() bool {
+	if g.fresh {
+		g.fresh = false
+		return false
+	}
+	g.p("{")
+
+	return true
+}
+
+// close ends the block started by open.
+func (g *generator) close(opened bool) {
+	if opened {
+		g.p("}")
+	}
+}
+
+// index returns the name of the index variable of the current loop.
+func (g *generator) index() string {
+	return fmt.Sprintf("i%d", g.loops)
+}
+
+// elemSize returns the memory size of t, or 1 if it is zero.
+func elemSize(t types.Type) int64 {
+	return max(1, sizes.Sizeof(t))
+}
+
+// primitive returns the name of the Write/Read methods and the go type of a basic type.
+func primitive(b *types.Basic) (string, string, bool) {
+	switch b.Kind() {
+	case types.Bool:
+		return "Bool", "bool", true
+	case types.Int8, types.Uint8:
+		return "U8", "uint8", true
+	case types.Int16, types.Uint16:
+		return "U16", "uint16", true
+	case types.Int32, types.Uint32:
+		return "U32", "uint32", true
+	case types.Int64, types.Uint64:
+		return "U64", "uint64", true
+	case types.String:
+		return "String", "string", true
+	default:
+		return "", "", false
+	}
+}
+
+// isStd checks if the package of path is in the standard library.
+func isStd(path string) bool {
+	return !strings.Contains(strings.Split(path, "/")[0], ".")
+}
+
+func isByte(t types.Type) bool {
+	return types.Identical(t, types.Typ[types.Uint8])
+}
+
+func (g *generator) generateMethods(t *target) error {
+	g.imports[bcsPath] = "bcs"
+
+	g.p("// MarshalBCSTo encodes %s into the encoder, see [bcs.MarshalerTo].", t.name)
+	g.p("func (v %s) MarshalBCSTo(e *bcs.Encoder) error {", t.name)
+	g.check("e.Enter()")
+	g.p("defer e.Leave()")
+	g.p("")
+	var err error
+	if t.isEnum {
+		err = g.encodeEnum(t)
+	} else {
+		err = g.encodeStruct(t)
+	}
+	if err != nil {
+		return err
+	}
+	g.p("}")
+	g.p("")
+
+	g.p("// UnmarshalBCSFrom decodes %s from the decoder, see [bcs.UnmarshalerFrom].", t.name)
+	g.p("func (v *%s) UnmarshalBCSFrom(d *bcs.Decoder) error {", t.name)
+	g.check("d.Enter()")
+	g.p("defer d.Leave()")
+	g.p("")
+	if t.isEnum {
+		err = g.decodeEnum(t)
+	} else {
+		err = g.decodeStruct(t)
+	}
+	if err != nil {
+		return err
+	}
+	g.p("}")
+	g.p("")
+
+	return nil
+}
+
+func (g *generator) encodeStruct(t *target) error {
+	for _, f := range t.fields {
+		if cond, ok := versionCond("e", f); ok {
+			g.p("if %s {", cond)
+			g.fresh = true
+		}
+		g.pushField(f.name)
+		err := g.encodeField(f)
+		g.pop()
+		if err != nil {
+			return fmt.Errorf("field %s: %w", f.name, err)
+		}
+		if _, ok := versionCond("e", f); ok {
+			g.p("}")
+		}
+	}
+	g.p("")
+	g.p("return nil")
+
+	return nil
+}
+
+func (g *generator) encodeField(f field) error {
+	x := "v." + f.name
+	g.checkConstraints(x, f.typ, f.constraints)
+	if !f.optional {
+		return g.encode(x, f.typ)
+	}
+
+	g.check("e.WriteOptionTag(%s != nil)", x)
+	g.p("if %s != nil {", x)
+	if err := g.encode("*"+x, f.typ.Underlying().(*types.Pointer).Elem()); err != nil {
+		return err
+	}
+	g.p("}")
+
+	return nil
+}
+
+// versionCond returns the condition the field is in the schema version of the encoder or decoder named x,
+// false if the field is in all versions, the same as the bcs package.
+func versionCond(x string, f field) (string, bool) {
+	switch {
+	case f.until != 0 && f.since != 0:
+		return fmt.Sprintf("%s.Version() != bcs.LatestVersion && %s.Version() >= %d && %s.Version() <= %d", x, x, f.since, x, f.until), true
+	case f.until != 0:
+		return fmt.Sprintf("%s.Version() != bcs.LatestVersion && %s.Version() <= %d", x, x, f.until), true
+	case f.since != 0:
+		return fmt.Sprintf("%s.Version() == bcs.LatestVersion || %s.Version() >= %d", x, x, f.since), true
+	default:
+		return "", false
+	}
+}
+
+func (g *generator) encodeEnum(t *target) error {
+	g.imports["fmt"] = "fmt"
+
+	g.p("switch {")
+	for _, f := range t.fields {
+		x := "v." + f.name
+		g.p("case %s != nil:", x)
+		g.pushField(f.name)
+		g.checkConstraints(x, f.typ, f.constraints)
+		g.check("e.WriteVariant(%d)", f.index)
+		if ptr, ok := f.typ.Underlying().(*types.Pointer); ok {
+			if err := g.encode("*"+x, ptr.Elem()); err != nil {
+				return fmt.Errorf("field %s: %w", f.name, err)
+			}
+		} else {
+			g.checkNested("e.Encode(%s)", x)
+		}
+		g.pop()
+	}
+	g.p("default:")
+	g.p("return fmt.Errorf(\"no field is set in the enum\")")
+	g.p("}")
+	g.p("")
+	g.p("return nil")
+
+	return nil
+}
+
+// encode writes the code encoding x, which is an addressable expression of type t.
+func (g *generator) encode(x string, t types.Type) error {
+	// the encoder calls the hook before encoding the value.
+	if hasHook(t, g.bcs.preparer) {
+		g.checkNested("e.Encode(%s)", addr(x))
+		return nil
+	}
+	if g.isTarget(t) {
+		g.checkNested("%s.MarshalBCSTo(e)", receiver(x))
+		return nil
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Pointer:
+		// nil pointers are encoded as the zero value.
+		g.p("if %s == nil {", x)
+		g.checkNested("e.Encode(new(%s))", g.typeString(u.Elem()))
+		g.p("} else {")
+		if err := g.encode("*"+x, u.Elem()); err != nil {
+			return err
+		}
+		g.p("}")
+		return nil
+	case *types.Interface:
+		g.checkNested("e.Encode(%s)", addr(x))
+		return nil
+	}
+
+	switch {
+	case implements(t, g.bcs.marshalerTo):
+		g.checkNested("%s.MarshalBCSTo(e)", receiver(x))
+		return nil
+	case implements(t, g.bcs.marshaler), g.bcs.isEnum(t):
+		g.checkNested("e.Encode(%s)", addr(x))
+		return nil
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		name, basic, ok := primitive(u)
+		if !ok {
+			return fmt.Errorf("unsupported type %s", t.String())
+		}
+		g.check("e.Write%s(%s)", name, conv(basic, x, t))
+	case *types.Slice:
+		if isByte(u.Elem()) {
+			g.check("e.WriteBytes(%s)", conv("[]byte", x, t))
+			return nil
+		}
+		i := g.index()
+		g.check("e.WriteLength(len(%s))", x)
+		g.p("for %s := range %s {", i, x)
+		g.loops++
+		g.pushIndex(i)
+		err := g.encode(operand(x)+"["+i+"]", u.Elem())
+		g.pop()
+		g.loops--
+		if err != nil {
+			return err
+		}
+		g.p("}")
+	case *types.Array:
+		if isByte(u.Elem()) {
+			g.check("e.WriteFixedBytes(%s[:])", operand(x))
+			return nil
+		}
+		i := g.index()
+		g.p("for %s := range %s {", i, x)
+		g.loops++
+		g.pushIndex(i)
+		err := g.encode(operand(x)+"["+i+"]", u.Elem())
+		g.pop()
+		g.loops--
+		if err != nil {
+			return err
+		}
+		g.p("}")
+	case *types.Struct:
+		g.checkNested("e.Encode(%s)", addr(x))
+	default:
+		return fmt.Errorf("unsupported type %s", t.String())
+	}
+
+	return nil
+}
+
+func (g *generator) decodeStruct(t *target) error {
+	for _, f := range t.fields {
+		if cond, ok := versionCond("d", f); ok {
+			g.p("if %s {", cond)
+			g.fresh = true
+		}
+		if f.nocopy {
+			// zero copy is on while the field is decoded, and restored afterwards.
+			g.p("if err := func() error {")
+			g.p("if !d.ZeroCopy() {")
+			g.p("d.SetZeroCopy(true)")
+			g.p("defer d.SetZeroCopy(false)")
+			g.p("}")
+		}
+		g.pushField(f.name)
+		err := g.decodeField(f)
+		g.pop()
+		if err != nil {
+			return fmt.Errorf("field %s: %w", f.name, err)
+		}
+		if f.nocopy {
+			g.p("return nil")
+			g.p("}(); err != nil {")
+			g.p("return err")
+			g.p("}")
+		}
+		if _, ok := versionCond("d", f); ok {
+			// the fields not in the version are reset.
+			g.p("} else {")
+			g.p("v.%s = *new(%s)", f.name, g.typeString(f.typ))
+			g.p("}")
+		}
+	}
+	g.p("")
+	g.p("return nil")
+
+	return nil
+}
+
+func (g *generator) decodeField(f field) error {
+	x := "v." + f.name
+	c := g.limitField(f)
+	if !f.optional {
+		if err := g.decode(x, f.typ); err != nil {
+			return err
+		}
+		g.checkConstraints(x, f.typ, c)
+		return nil
+	}
+
+	elem := f.typ.Underlying().(*types.Pointer).Elem()
+	opened := g.open()
+	g.p("some, err := d.ReadOptionTag()")
+	g.p("if err != nil {")
+	g.p("return err")
+	g.p("}")
+	g.p("if some {")
+	g.p("%s = new(%s)", x, g.typeString(elem))
+	g.fresh = true
+	if err := g.decode("*"+x, elem); err != nil {
+		return err
+	}
+	g.p("} else {")
+	g.p("%s = nil", x)
+	g.p("}")
+	g.close(opened)
+	g.checkConstraints(x, f.typ, c)
+
+	return nil
+}
+
+// limitField sets the maxlen of the field to be checked when the length is decoded, and returns the constraints
+// to check after the field is decoded. Values with [bcs.BCSValidator] are decoded by the decoder, and their length
+// can only be checked afterwards.
+func (g *generator) limitField(f field) constraints {
+	c := f.constraints
+	if c.maxLen < 0 || hasHook(deref(f.typ), g.bcs.validator) {
+		return c
+	}
+
+	g.limit = c.maxLen
+	c.maxLen = -1
+
+	return c
+}
+
+func (g *generator) decodeEnum(t *target) error {
+	g.imports["fmt"] = "fmt"
+
+	g.p("id, err := d.ReadVariant()")
+	g.p("if err != nil {")
+	g.p("return err")
+	g.p("}")
+	g.p("")
+	g.p("switch id {")
+	for _, f := range t.fields {
+		x := "v." + f.name
+		g.p("case %d:", f.index)
+		g.pushField(f.name)
+		if ptr, ok := f.typ.Underlying().(*types.Pointer); ok {
+			c := g.limitField(f)
+			if err := g.decode(x, ptr); err != nil {
+				return fmt.Errorf("field %s: %w", f.name, err)
+			}
+			g.checkConstraints(x, f.typ, c)
+		} else {
+			g.decodeNested(addr(x))
+		}
+		g.pop()
+	}
+	g.p("default:")
+	g.p("if id < %d {", t.st.NumFields())
+	g.p("return fmt.Errorf(\"enum field %%d is unexported or ignored\", id)")
+	g.p("}")
+	g.p("return fmt.Errorf(\"enum field %%d is out of range\", id)")
+	g.p("}")
+	g.p("")
+	g.p("return nil")
+
+	return nil
+}
+
+// decode writes the code decoding into x, which is an addressable expression of type t.
+func (g *generator) decode(x string, t types.Type) error {
+	limit := g.limit
+	g.limit = -1
+
+	// the decoder calls the hook after decoding the value.
+	if hasHook(t, g.bcs.validator) {
+		g.decodeNested(addr(x))
+		return nil
+	}
+	if g.isTarget(t) {
+		g.checkNested("%s.UnmarshalBCSFrom(d)", receiver(x))
+		return nil
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Pointer:
+		g.p("if %s == nil {", x)
+		g.p("%s = new(%s)", x, g.typeString(u.Elem()))
+		g.p("}")
+		g.limit = limit
+		return g.decode("*"+x, u.Elem())
+	case *types.Interface:
+		g.decodeNested(addr(x))
+		return nil
+	}
+
+	switch {
+	case implements(t, g.bcs.unmarshalerFrom):
+		g.checkNested("%s.UnmarshalBCSFrom(d)", receiver(x))
+		return nil
+	case implements(t, g.bcs.unmarshaler), g.bcs.isEnum(t):
+		g.decodeNested(addr(x))
+		return nil
+	}
+
+	if u, ok := t.Underlying().(*types.Slice); limit >= 0 && (isBasic(t, types.IsString) || ok && isByte(u.Elem())) {
+		// the length is checked before the bytes are allocated, and the bytes alias the input under zero copy.
+		opened := g.open()
+		g.readLength(limit)
+		read, basic := "ReadBytesOfLength", "[]byte"
+		if isBasic(t, types.IsString) {
+			read, basic = "ReadStringOfLength", "string"
+		}
+		g.p("u, err := d.%s(n)", read)
+		g.p("if err != nil {")
+		g.p("return err")
+		g.p("}")
+		if isType(t, basic) {
+			g.p("%s = u", x)
+		} else {
+			g.p("%s = %s(u)", x, g.typeString(t))
+		}
+		g.close(opened)
+		return nil
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		name, basic, ok := primitive(u)
+		if !ok {
+			return fmt.Errorf("unsupported type %s", t.String())
+		}
+		opened := g.open()
+		g.p("u, err := d.Read%s()", name)
+		g.p("if err != nil {")
+		g.p("return err")
+		g.p("}")
+		if isType(t, basic) {
+			g.p("%s = u", x)
+		} else {
+			g.p("%s = %s(u)", x, g.typeString(t))
+		}
+		g.close(opened)
+	case *types.Slice:
+		if isByte(u.Elem()) {
+			opened := g.open()
+			g.p("u, err := d.ReadBytes()")
+			g.p("if err != nil {")
+			g.p("return err")
+			g.p("}")
+			if isType(t, "[]byte") {
+				g.p("%s = u", x)
+			} else {
+				g.p("%s = %s(u)", x, g.typeString(t))
+			}
+			g.close(opened)
+			return nil
+		}
+		// preallocate no more than maxPrealloc bytes, and reuse the capacity of x.
+		i := g.index()
+		opened := g.open()
+		g.readLength(limit)
+		g.p("if %s == nil || cap(%s) < n {", x, x)
+		g.p("%s = make(%s, 0, min(n, %d))", x, g.typeString(t), max(1, maxPrealloc/elemSize(u.Elem())))
+		g.p("} else {")
+		g.p("%s = %s[:0]", x, operand(x))
+		g.p("}")
+		g.p("for %s := 0; %s < n; %s++ {", i, i, i)
+		g.p("%s = append(%s, *new(%s))", x, x, g.typeString(u.Elem()))
+		g.fresh = true
+		g.loops++
+		g.pushIndex(i)
+		err := g.decode(operand(x)+"["+i+"]", u.Elem())
+		g.pop()
+		g.loops--
+		if err != nil {
+			return err
+		}
+		g.p("}")
+		g.close(opened)
+	case *types.Array:
+		if isByte(u.Elem()) {
+			g.check("d.ReadFixedBytes(%s[:])", operand(x))
+			return nil
+		}
+		i := g.index()
+		g.p("for %s := range %s {", i, x)
+		g.fresh = true
+		g.loops++
+		g.pushIndex(i)
+		err := g.decode(operand(x)+"["+i+"]", u.Elem())
+		g.pop()
+		g.loops--
+		if err != nil {
+			return err
+		}
+		g.p("}")
+	case *types.Struct:
+		g.decodeNested(addr(x))
+	default:
+		return fmt.Errorf("unsupported type %s", t.String())
+	}
+
+	return nil
+}
+
+// readLength writes the code reading the length of a vector into n, and checks it against the limit if it is set.
+func (g *generator) readLength(limit int) {
+	g.p("n, err := d.ReadLength()")
+	g.p("if err != nil {")
+	g.p("return err")
+	g.p("}")
+	if limit >= 0 {
+		g.p("if n > %d {", limit)
+		g.fail("\"length %%d exceeds maxlen %d\", n", limit)
+		g.p("}")
+	}
+}
+
+// finish formats the code written so far with the package clause and the imports, and resets the buffer.
+func (g *generator) finish(header string) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString(header)
+	fmt.Fprintf(&b, "package %s\n\n", g.pkg.Name())
+
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if isStd(paths[i]) != isStd(paths[j]) {
+			return isStd(paths[i])
+		}
+		return paths[i] < paths[j]
+	})
+	b.WriteString("import (\n")
+	for i, path := range paths {
+		// standard packages are grouped before the others.
+		if i > 0 && isStd(paths[i-1]) && !isStd(path) {
+			b.WriteString("\n")
+		}
+		name := g.imports[path]
+		if name == path[strings.LastIndex(path, "/")+1:] {
+			fmt.Fprintf(&b, "%q\n", path)
+		} else {
+			fmt.Fprintf(&b, "%s %q\n", name, path)
+		}
+	}
+	b.WriteString(")\n\n")
+	b.Write(g.buf.Bytes())
+	g.buf.Reset()
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w\n%s", err, b.String())
+	}
+
+	return src, nil
+}
@@ -0,0 +1,110 @@
+// Package example has types covering the rules of bcs, to test the code generated by bcsgen.
+package example
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+//go:generate go run github.com/fardream/go-bcs/cmd/bcsgen -type Transaction,Payload,Transfer,Call,Node
+
+type Address [32]byte
+
+func (a Address) String() string {
+	return fmt.Sprintf("0x%s", hex.EncodeToString(a[:]))
+}
+
+type Kind uint8
+
+// Name has customized marshaler, which is encoded with reflection by the generated code.
+type Name struct {
+	s string
+}
+
+func (n Name) MarshalBCS() ([]byte, error) {
+	return bcs.Marshal(n.s)
+}
+
+func (n *Name) UnmarshalBCS(r io.Reader) (int, error) {
+	return bcs.NewDecoder(r).Decode(&n.s)
+}
+
+// Meta is not in the list of types, and is encoded with reflection by the generated code.
+type Meta struct {
+	Labels []string
+	Hash   [4]byte
+}
+
+type Transaction struct {
+	Sender    Address
+	Sequence  uint64
+	GasPrice  bcs.Uint128
+	Expire    int64
+	Kind      Kind `bcs:"max=200"`
+	Flags     [3]int16
+	Payload   Payload
+	Signature []byte  `bcs:"nocopy,maxlen=64"`
+	Memo      *string `bcs:"optional,utf8,maxlen=64"`
+	Fee       *uint32
+	Tip       *uint64 `bcs:"optional"`
+	Meta      Meta
+	Name      Name
+	Any       any `bcs:"-"`
+	cache     []byte
+}
+
+// Payload is an enum.
+type Payload struct {
+	Transfer  *Transfer
+	Call      *Call
+	Ignored   *uint8 `bcs:"-"`
+	Raw       *[]byte
+	Batch     *[]Payload
+	Something any
+}
+
+func (Payload) IsBcsEnum() {}
+
+type Transfer struct {
+	To      []Address
+	Amounts []bcs.Uint128
+	Enabled bool
+}
+
+// ValidateBCS checks each address has an amount, see [bcs.BCSValidator].
+func (t *Transfer) ValidateBCS() error {
+	if len(t.To) != len(t.Amounts) {
+		return fmt.Errorf("%d addresses for %d amounts", len(t.To), len(t.Amounts))
+	}
+
+	return nil
+}
+
+type Call struct {
+	Module   string `bcs:"maxlen=32,ascii"`
+	Function string
+	Args     [][]byte `bcs:"maxlen=2"`
+	Nested   [][2]*Transfer
+	Gas      uint64 `bcs:"since=2"`
+	Legacy   *uint8 `bcs:"optional,until=1"`
+}
+
+// PrepareBCS checks the arguments are given to a function, see [bcs.BCSPreparer].
+func (c Call) PrepareBCS() error {
+	if c.Function == "" && len(c.Args) > 0 {
+		return fmt.Errorf("%d arguments without function", len(c.Args))
+	}
+
+	return nil
+}
+
+// Node is a recursive type.
+type Node struct {
+	Value    int32
+	Left     *Node `bcs:"optional"`
+	Right    *Node `bcs:"optional"`
+	Children []Node
+}
@@ -0,0 +1,72 @@
+package example
+
+import (
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+func benchmarkTransaction() Transaction {
+	memo := "memo"
+	return Transaction{
+		Sequence: 42,
+		Payload: Payload{
+			Call: &Call{
+				Module:   "coin",
+				Function: "transfer",
+				Args:     [][]byte{{1, 2, 3}, make([]byte, 32)},
+				Nested:   [][2]*Transfer{{{Amounts: make([]bcs.Uint128, 4)}, {To: make([]Address, 2)}}},
+			},
+		},
+		Signature: make([]byte, 64),
+		Memo:      &memo,
+	}
+}
+
+func BenchmarkGenerated(b *testing.B) {
+	v := benchmarkTransaction()
+	encoded := bcs.MustMarshal(&v)
+
+	b.Run("encode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := bcs.Marshal(&v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("decode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var decoded Transaction
+			if err := bcs.UnmarshalAll(encoded, &decoded); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkReflection encodes the same value as [BenchmarkGenerated] with reflection for the top level struct.
+func BenchmarkReflection(b *testing.B) {
+	v := benchmarkTransaction()
+	shadow := (*bcsgenShadowTransaction)(&v)
+	encoded := bcs.MustMarshal(shadow)
+
+	b.Run("encode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := bcs.Marshal(shadow); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("decode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var decoded bcsgenShadowTransaction
+			if err := bcs.UnmarshalAll(encoded, &decoded); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package example
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/fardream/go-bcs/bcs"
+)
+
+func TestTransaction_nocopy(t *testing.T) {
+	memo := "memo"
+	v := Transaction{
+		Payload:   Payload{Call: &Call{Module: "coin", Function: "transfer"}},
+		Signature: make([]byte, 64),
+		Memo:      &memo,
+	}
+	for i := range v.Signature {
+		v.Signature[i] = byte(i)
+	}
+	encoded := bcs.MustMarshal(&v)
+
+	var decoded Transaction
+	if err := bcs.UnmarshalAll(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	i := bytes.Index(encoded, v.Signature)
+	if &decoded.Signature[0] != &encoded[i] {
+		t.Fatal("want the signature to alias the input")
+	}
+	// zero copy is only on for the signature.
+	encoded[bytes.Index(encoded, []byte(memo))] = 'x'
+	if *decoded.Memo != memo {
+		t.Fatalf("want the memo copied, got %q", *decoded.Memo)
+	}
+}
+
+func TestTransaction_errorPath(t *testing.T) {
+	batch := []Payload{{Call: &Call{Module: "coin"}}, {Call: &Call{Module: "co\xffn"}}}
+	v := Transaction{Payload: Payload{Batch: &batch}}
+	const path = "Payload.Batch[1].Call.Module"
+
+	var ve *bcs.ValidationError
+	if _, err := bcs.Marshal(&v); !errors.As(err, &ve) || ve.Path != path {
+		t.Fatalf("want validation error at %s, got %v", path, err)
+	}
+
+	batch[1].Call.Module = "coin"
+	encoded := bcs.MustMarshal(&v)
+	encoded[bytes.LastIndex(encoded, []byte("coin"))+2] = 0xff
+	var decoded Transaction
+	if err := bcs.UnmarshalAll(encoded, &decoded); !errors.As(err, &ve) || ve.Path != path {
+		t.Fatalf("want validation error at %s, got %v", path, err)
+	}
+}
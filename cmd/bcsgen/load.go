@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strings"
+)
+
+// loadPackage parses and type checks the package in dir, and returns it with the bcs package,
+// which is imported by the same importer so the types are identical to the types the package uses.
+//
+// Files generated by bcsgen are skipped, since they may be out of date with the types.
+// The other files may use the generated methods, so type errors are tolerated, and only
+// reported if the types to be generated depend on them, see [generator.checkValid].
+func loadPackage(dir string) (*types.Package, *types.Package, []error, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if isGeneratedByBcsgen(f) {
+			continue
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil, nil, nil, fmt.Errorf("no go files in %s", dir)
+	}
+
+	var typeErrors []error
+	imp := importer.ForCompiler(fset, "source", nil).(types.ImporterFrom)
+	conf := types.Config{
+		Importer: imp,
+		Error:    func(err error) { typeErrors = append(typeErrors, err) },
+	}
+	pkg, _ := conf.Check(bp.ImportPath, fset, files, nil)
+
+	bcsPkg, err := imp.ImportFrom(bcsPath, bp.Dir, 0)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("cannot import %s: %w", bcsPath, err)
+	}
+
+	return pkg, bcsPkg, typeErrors, nil
+}
+
+// isGeneratedByBcsgen checks if the file is generated by bcsgen.
+func isGeneratedByBcsgen(f *ast.File) bool {
+	if !ast.IsGenerated(f) {
+		return false
+	}
+
+	for _, c := range f.Comments {
+		if strings.Contains(c.Text(), "Code generated by \"bcsgen") {
+			return true
+		}
+	}
+
+	return false
+}
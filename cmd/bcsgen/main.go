@@ -0,0 +1,88 @@
+// Command bcsgen generates static bcs encoding and decoding methods for struct types,
+// so the values are encoded and decoded without going through reflection.
+//
+// For each type, bcsgen generates MarshalBCSTo and UnmarshalBCSFrom, which implement [bcs.MarshalerTo]
+// and [bcs.UnmarshalerFrom], and are picked up by [bcs.Marshal], [bcs.Unmarshal], [bcs.Encoder] and [bcs.Decoder].
+// The generated code follows the same rules as [bcs.Marshal] and produces the same bytes:
+// bcs tags, [bcs.Enum], optional fields, arrays and slices, and customized marshalers are all respected.
+//
+// Typical usage is with go generate:
+//
+//	//go:generate go run github.com/fardream/go-bcs/cmd/bcsgen -type Transaction,Payload
+//
+// The types are written to <type>_bcs.go, named after the first type, and a test is written to
+// <type>_bcs_test.go, which fills the values randomly and cross-checks the generated code against reflection.
+//
+// Values the generator doesn't handle inline, such as interfaces, types implementing [bcs.Marshaler],
+// [bcs.Enum] types not in the list of types, and structs not in the list of types, are encoded and decoded
+// with [bcs.Encoder.Encode] and [bcs.Decoder.Decode], and are still correct, only slower.
+// Put all the structs of a package in the list of types to avoid reflection completely.
+// Values with [bcs.BCSPreparer] or [bcs.BCSValidator] hooks are also encoded and decoded with the encoder and decoder,
+// so the hooks are called, but the field paths of their errors start from the value.
+//
+// Usage:
+//
+//	bcsgen [flags] -type T1,T2 [directory]
+//
+// The directory defaults to the current directory.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	typeNames = flag.String("type", "", "comma-separated list of type names; must be set")
+	output    = flag.String("output", "", "output file name; default <directory>/<type>_bcs.go")
+	tests     = flag.Bool("tests", true, "generate the test cross-checking the generated code with reflection")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage of bcsgen:\n")
+	fmt.Fprintf(os.Stderr, "\tbcsgen [flags] -type T1,T2 [directory]\n")
+	fmt.Fprintf(os.Stderr, "Flags:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("bcsgen: ")
+	flag.Usage = usage
+	flag.Parse()
+
+	if *typeNames == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+
+	names := strings.Split(*typeNames, ",")
+	code, test, err := generate(dir, names)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	outputName := *output
+	if outputName == "" {
+		outputName = filepath.Join(dir, strings.ToLower(names[0])+"_bcs.go")
+	}
+	if err := os.WriteFile(outputName, code, 0o644); err != nil {
+		log.Fatalf("writing output: %s", err)
+	}
+
+	if *tests {
+		testName := strings.TrimSuffix(outputName, ".go") + "_test.go"
+		if err := os.WriteFile(testName, test, 0o644); err != nil {
+			log.Fatalf("writing test: %s", err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	dir := filepath.Join("internal", "example")
+	code, test, err := generate(dir, []string{"Transaction", "Payload", "Transfer", "Call", "Node"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, generated := range map[string][]byte{"transaction_bcs.go": code, "transaction_bcs_test.go": test} {
+		existing, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(existing, generated) {
+			t.Errorf("%s is out of date, run go generate in %s", name, dir)
+		}
+	}
+}
+
+func TestGenerate_invalid(t *testing.T) {
+	cases := map[string]string{
+		"UnknownTag":    "unknown tag: whatever",
+		"OptionalValue": "optional field can only be pointer",
+		"EnumValue":     "enum only supports fields that are either pointers or interfaces",
+		"BadConstraint": "tag maxlen can only be used on vectors and strings",
+		"BadVersion":    "field is removed before it is added",
+		"Unsupported":   "unsupported type map[string]uint8",
+		"NotStruct":     "NotStruct is not a struct",
+		"Customized":    "Customized already implements MarshalBCS",
+		"Conflicting":   "Conflicting already has UnmarshalBCSFrom",
+		"Missing":       "cannot find type Missing",
+	}
+
+	dir := filepath.Join("testdata", "invalid")
+	for name, expected := range cases {
+		_, _, err := generate(dir, []string{name})
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: want error containing %q, got: %v", name, expected, err)
+		}
+	}
+}
+
+func TestGenerate_wrongSignature(t *testing.T) {
+	code, _, err := generate(filepath.Join("testdata", "signature"), []string{"Leveled"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"e.WriteU8(uint8(v.Level))", "d.ReadU8()"} {
+		if !bytes.Contains(code, []byte(want)) {
+			t.Errorf("want %s in the generated code:\n%s", want, code)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package invalid
+
+type UnknownTag struct {
+	A uint8 `bcs:"whatever"`
+}
+
+type OptionalValue struct {
+	A uint8 `bcs:"optional"`
+}
+
+type EnumValue struct {
+	A uint8
+}
+
+func (EnumValue) IsBcsEnum() {}
+
+type BadConstraint struct {
+	A uint8 `bcs:"maxlen=1"`
+}
+
+type BadVersion struct {
+	A uint8 `bcs:"since=2,until=1"`
+}
+
+type Unsupported struct {
+	A map[string]uint8
+}
+
+type NotStruct []uint8
+
+type Customized struct {
+	A uint8
+}
+
+func (c Customized) MarshalBCS() ([]byte, error) {
+	return []byte{c.A}, nil
+}
+
+type Conflicting struct {
+	A uint8
+}
+
+func (c *Conflicting) UnmarshalBCSFrom() {}
@@ -0,0 +1,12 @@
+package signature
+
+// Level has the methods of the customized marshalers with the wrong signatures, so it is encoded as uint8.
+type Level uint8
+
+func (Level) MarshalBCSTo() error { return nil }
+
+func (*Level) UnmarshalBCS(data []byte) (int, error) { return 0, nil }
+
+type Leveled struct {
+	Level Level
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"go/types"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxFillDepth is the depth after which pointers, slices and enums are left empty
+// when filling values, so recursive types are finite.
+const maxFillDepth = 3
+
+// generateTest writes the test cross-checking the generated methods of t with reflection.
+//
+// The shadow type has the same fields as t but none of its methods, so [bcs.Marshal] encodes
+// it with reflection. The values are filled randomly, and the generated code must produce the same bytes
+// as the shadow type, and decode them back to values which encode to the same bytes, in every schema version
+// returned by [generator.versions]. The hooks of t are delegated to by the shadow type, see [generator.generateHook].
+func (g *generator) generateTest(t *target) {
+	g.imports[bcsPath] = "bcs"
+	shadow := "bcsgenShadow" + t.name
+
+	g.p("// %s has the same fields as %s without the generated methods, so it is encoded with reflection.", shadow, t.name)
+	g.p("type %s %s", shadow, t.name)
+	g.p("")
+	if t.isEnum {
+		g.p("func (%s) IsBcsEnum() {}", shadow)
+		g.p("")
+	}
+	g.generateHook(t, shadow, "PrepareBCS", g.bcs.preparer)
+	g.generateHook(t, shadow, "ValidateBCS", g.bcs.validator)
+
+	g.p("func TestBcsgen%s(t *testing.T) {", t.name)
+	g.p("marshal := func(v any, version int) ([]byte, error) {")
+	g.p("var buf bytes.Buffer")
+	g.p("e := bcs.NewEncoder(&buf)")
+	g.p("e.SetVersion(version)")
+	g.p("err := e.Encode(v)")
+	g.p("return buf.Bytes(), err")
+	g.p("}")
+	g.p("unmarshal := func(b []byte, v any, version int) error {")
+	g.p("d := bcs.NewBytesDecoder(b)")
+	g.p("d.SetVersion(version)")
+	g.p("n, err := d.Decode(v)")
+	g.p("if err == nil && n != len(b) {")
+	g.imports["fmt"] = "fmt"
+	g.p("err = fmt.Errorf(\"did not unmarshal all bytes, got %%d expected %%d\", n, len(b))")
+	g.p("}")
+	g.p("return err")
+	g.p("}")
+	g.p("")
+	g.p("r := rand.New(rand.NewSource(1))")
+	g.p("for i := 0; i < 64; i++ {")
+	g.p("var v %s", t.name)
+	g.p("bcsgenFill%s(r, &v, 0)", t.name)
+	g.p("")
+	g.p("for _, version := range %s {", g.versions())
+	g.p("want, wantErr := marshal((*%s)(&v), version)", shadow)
+	g.p("got, err := marshal(&v, version)")
+	g.p("if (err != nil) != (wantErr != nil) {")
+	g.p("t.Fatalf(\"reflection error: %%v, generated error: %%v\", wantErr, err)")
+	g.p("}")
+	g.p("if err != nil {")
+	g.p("continue")
+	g.p("}")
+	g.p("if !bytes.Equal(got, want) {")
+	g.p("t.Fatalf(\"version %%d, want: %%v\\ngot:  %%v\", version, want, got)")
+	g.p("}")
+	g.p("")
+	g.p("var decoded %s", t.name)
+	g.p("if err := unmarshal(got, &decoded, version); err != nil {")
+	g.imports["errors"] = "errors"
+	g.p("// the random value may be rejected by the validators.")
+	g.p("if errors.As(err, new(*bcs.ValidationError)) {")
+	g.p("continue")
+	g.p("}")
+	g.p("t.Fatal(err)")
+	g.p("}")
+	g.p("if b, err := marshal(&decoded, version); err != nil || !bytes.Equal(b, want) {")
+	g.p("t.Fatalf(\"version %%d, decoded value doesn't round trip: %%v, %%v\", version, b, err)")
+	g.p("}")
+	g.p("var decodedShadow %s", shadow)
+	g.p("if err := unmarshal(got, &decodedShadow, version); err != nil {")
+	g.p("t.Fatal(err)")
+	g.p("}")
+	g.p("if b, err := marshal(&decodedShadow, version); err != nil || !bytes.Equal(b, want) {")
+	g.p("t.Fatalf(\"version %%d, value decoded with reflection doesn't round trip: %%v, %%v\", version, b, err)")
+	g.p("}")
+	g.p("}")
+	g.p("}")
+	g.p("}")
+	g.p("")
+
+	g.generateFill(t)
+}
+
+// generateHook writes the method of the shadow type delegating to the hook of t, so the shadow type
+// is prepared or validated the same as t. The method has the same receiver as the hook.
+func (g *generator) generateHook(t *target, shadow, method string, iface *types.Interface) {
+	if !hasHook(t.named, iface) {
+		return
+	}
+
+	if types.Implements(t.named, iface) {
+		g.p("func (v %s) %s() error {", shadow, method)
+		g.p("return %s(v).%s()", t.name, method)
+	} else {
+		g.p("func (v *%s) %s() error {", shadow, method)
+		g.p("return (*%s)(v).%s()", t.name, method)
+	}
+	g.p("}")
+	g.p("")
+}
+
+// versions returns the schema versions the test is run with: the latest version, and the versions
+// before, at, and after the since and until tags of the targets.
+func (g *generator) versions() string {
+	set := map[int]bool{}
+	for _, t := range g.targets {
+		for _, f := range t.fields {
+			for _, v := range []int{f.since, f.until} {
+				if v > 0 {
+					set[v-1], set[v], set[v+1] = true, true, true
+				}
+			}
+		}
+	}
+	delete(set, 0)
+
+	versions := []string{"bcs.LatestVersion"}
+	for _, v := range slices.Sorted(maps.Keys(set)) {
+		versions = append(versions, strconv.Itoa(v))
+	}
+
+	return "[]int{" + strings.Join(versions, ", ") + "}"
+}
+
+// generateFill writes the function filling the values of t randomly.
+func (g *generator) generateFill(t *target) {
+	g.p("func bcsgenFill%s(r *rand.Rand, v *%s, depth int) {", t.name, t.name)
+	if t.isEnum {
+		g.p("if depth > %d {", maxFillDepth)
+		g.p("return")
+		g.p("}")
+		g.p("switch r.Intn(%d) {", max(1, len(t.fields)))
+		for i, f := range t.fields {
+			g.p("case %d:", i)
+			g.fill("v."+f.name, f.typ, true)
+		}
+		g.p("}")
+	} else {
+		for _, f := range t.fields {
+			g.fill("v."+f.name, f.typ, false)
+		}
+	}
+	g.p("}")
+	g.p("")
+}
+
+// fill writes the code filling x of type t with random values. Values of types without known
+// structures, such as interfaces and types from other packages with customized marshalers, are left as zero.
+// Pointers are always allocated if force is true, for the variants of enums.
+func (g *generator) fill(x string, t types.Type, force bool) {
+	if named, ok := t.(*types.Named); ok && g.targets[named.Obj()] != nil {
+		g.p("bcsgenFill%s(r, %s, depth+1)", named.Obj().Name(), addr(x))
+		return
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Pointer:
+		if !force {
+			g.p("if depth < %d && r.Intn(4) > 0 {", maxFillDepth)
+		}
+		g.p("%s = new(%s)", x, g.typeString(u.Elem()))
+		g.fill("*"+x, u.Elem(), false)
+		if !force {
+			g.p("}")
+		}
+		return
+	case *types.Interface:
+		return
+	}
+
+	if !g.fillable(t) {
+		return
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch u.Kind() {
+		case types.Bool:
+			g.p("%s = r.Intn(2) == 1", x)
+		case types.String:
+			g.imports["fmt"] = "fmt"
+			g.p("%s = %s(fmt.Sprint(r.Uint64()))", x, g.typeString(t))
+		default:
+			g.p("%s = %s(r.Uint64())", x, g.typeString(t))
+		}
+	case *types.Slice:
+		i := g.index()
+		g.p("if depth < %d {", maxFillDepth)
+		g.p("%s = make(%s, r.Intn(4))", x, g.typeString(t))
+		if isByte(u.Elem()) {
+			g.p("r.Read(%s)", x)
+		} else if g.fillable(u.Elem()) {
+			g.p("for %s := range %s {", i, x)
+			g.loops++
+			g.fill(operand(x)+"["+i+"]", u.Elem(), false)
+			g.loops--
+			g.p("}")
+		}
+		g.p("}")
+	case *types.Array:
+		if isByte(u.Elem()) {
+			g.p("r.Read(%s[:])", operand(x))
+			return
+		}
+		i := g.index()
+		g.p("for %s := range %s {", i, x)
+		g.loops++
+		g.fill(operand(x)+"["+i+"]", u.Elem(), false)
+		g.loops--
+		g.p("}")
+	}
+}
+
+// fillable checks if fill writes any code for type t.
+func (g *generator) fillable(t types.Type) bool {
+	if g.isTarget(t) {
+		return true
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Pointer:
+		return true
+	case *types.Interface:
+		return false
+	case *types.Basic, *types.Slice:
+	case *types.Array:
+		if !g.fillable(u.Elem()) {
+			return false
+		}
+	default:
+		return false
+	}
+
+	return !g.bcs.customized(t) && !g.bcs.isEnum(t)
+}
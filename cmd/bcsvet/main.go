@@ -0,0 +1,17 @@
+// Command bcsvet checks the usage of the bcs package, see [analysis.Analyzer] for the checks.
+//
+// It can be run directly, or as a vet tool:
+//
+//	bcsvet ./...
+//	go vet -vettool=$(which bcsvet) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/fardream/go-bcs/bcs/analysis"
+)
+
+func main() {
+	singlechecker.Main(analysis.Analyzer)
+}
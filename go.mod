@@ -1,3 +1,13 @@
 module github.com/fardream/go-bcs
 
-go 1.22
+go 1.23.0
+
+require (
+	golang.org/x/tools v0.36.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	golang.org/x/mod v0.27.0 // indirect
+	golang.org/x/sync v0.16.0 // indirect
+)
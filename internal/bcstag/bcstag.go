@@ -0,0 +1,166 @@
+// Package bcstag parses the bcs tags of struct fields. The grammar is shared by the bcs package and the tools
+// checking and generating code for it, so they accept the same tags and agree on what the tags mean.
+package bcstag
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Name is the key of the bcs tag in the struct tags.
+const Name = "bcs"
+
+// LatestVersion is the schema version including the fields tagged with since and excluding the fields tagged with until.
+const LatestVersion = 0
+
+// Options are the options without values, and Keys are the options with values.
+var (
+	Options = []string{"optional", "nocopy", "utf8", "ascii", "-"}
+	Keys    = []string{"maxlen", "min", "max", "since", "until"}
+)
+
+// Tag is the parsed bcs tag of a field.
+type Tag struct {
+	Optional bool
+	Ignore   bool
+	NoCopy   bool
+	// UTF8 and ASCII require strings and []byte to be valid utf8 or ascii.
+	UTF8  bool
+	ASCII bool
+	// MaxLen is the max length of a vector or string, -1 if not set.
+	MaxLen int
+	// Min and Max are the bounds of integers as in the tag, empty if not set.
+	// They are checked against the size of the integer by [IntBounds] and [UintBounds].
+	Min, Max string
+	// Since and Until are the first and the last schema versions the field is in, 0 if not set.
+	Since, Until int
+}
+
+// UnknownError is the error of an option that is not known.
+type UnknownError struct {
+	// Option is the unknown option, with its value if any.
+	Option string
+	Tag    string
+}
+
+func (e *UnknownError) Error() string {
+	return fmt.Sprintf("unknown tag: %s in %s", e.Option, e.Tag)
+}
+
+// Parse parses the bcs tag of a field. The options after "-" are not checked, since the field is ignored.
+func Parse(tag string) (Tag, error) {
+	r := Tag{MaxLen: -1}
+	for _, seg := range strings.Split(tag, ",") {
+		seg = strings.TrimSpace(seg)
+		key, value, hasValue := strings.Cut(seg, "=")
+		switch {
+		case seg == "":
+		case seg == "optional":
+			r.Optional = true
+		case seg == "nocopy":
+			r.NoCopy = true
+		case seg == "-":
+			return Tag{Ignore: true, MaxLen: -1}, nil
+		case seg == "utf8":
+			r.UTF8 = true
+		case seg == "ascii":
+			r.ASCII = true
+		case hasValue && key == "maxlen":
+			maxLen, err := strconv.Atoi(value)
+			if err != nil || maxLen < 0 {
+				return Tag{}, fmt.Errorf("invalid maxlen: %s in %s", value, tag)
+			}
+			r.MaxLen = maxLen
+		case hasValue && (key == "min" || key == "max"):
+			if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+				if _, err := strconv.ParseUint(value, 10, 64); err != nil {
+					return Tag{}, fmt.Errorf("invalid %s: %s in %s", key, value, tag)
+				}
+			}
+			if key == "min" {
+				r.Min = value
+			} else {
+				r.Max = value
+			}
+		case hasValue && (key == "since" || key == "until"):
+			version, err := strconv.Atoi(value)
+			if err != nil || version <= 0 {
+				return Tag{}, fmt.Errorf("invalid version: %s in %s, versions must be positive", value, tag)
+			}
+			if key == "since" {
+				r.Since = version
+			} else {
+				r.Until = version
+			}
+		default:
+			return Tag{}, &UnknownError{Option: seg, Tag: tag}
+		}
+	}
+	if r.Until != 0 && r.Until < r.Since {
+		return Tag{}, fmt.Errorf("field is removed before it is added: %s", tag)
+	}
+
+	return r, nil
+}
+
+// HasConstraints checks if the tag declares any checks on the value.
+func (t Tag) HasConstraints() bool {
+	return t.MaxLen >= 0 || t.Min != "" || t.Max != "" || t.UTF8 || t.ASCII
+}
+
+// IsVersioned checks if the tag has since or until.
+func (t Tag) IsVersioned() bool {
+	return t.Since != 0 || t.Until != 0
+}
+
+// InVersion checks if the field added in since and removed after until is in the schema version.
+// Both bounds are inclusive, and 0 means the bound is not set. The fields removed in any version
+// are not in [LatestVersion].
+func InVersion(version, since, until int) bool {
+	if version == LatestVersion {
+		return until == 0
+	}
+
+	return version >= since && (until == 0 || version <= until)
+}
+
+// IntBounds parses the bounds of a signed integer of bits, which default to the range of the integer.
+func IntBounds(min, max string, bits int) (int64, int64, error) {
+	lo, hi := int64(-1)<<(bits-1), int64(1)<<(bits-1)-1
+	for _, b := range []struct {
+		s string
+		v *int64
+	}{{min, &lo}, {max, &hi}} {
+		if b.s == "" {
+			continue
+		}
+		v, err := strconv.ParseInt(b.s, 10, bits)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid bound %s: %w", b.s, err)
+		}
+		*b.v = v
+	}
+
+	return lo, hi, nil
+}
+
+// UintBounds parses the bounds of an unsigned integer of bits, which default to the range of the integer.
+func UintBounds(min, max string, bits int) (uint64, uint64, error) {
+	lo, hi := uint64(0), uint64(1)<<bits-1
+	for _, b := range []struct {
+		s string
+		v *uint64
+	}{{min, &lo}, {max, &hi}} {
+		if b.s == "" {
+			continue
+		}
+		v, err := strconv.ParseUint(b.s, 10, bits)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid bound %s: %w", b.s, err)
+		}
+		*b.v = v
+	}
+
+	return lo, hi, nil
+}
@@ -0,0 +1,78 @@
+package bcstag_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fardream/go-bcs/internal/bcstag"
+)
+
+func TestParse(t *testing.T) {
+	for _, c := range []struct {
+		tag  string
+		want bcstag.Tag
+	}{
+		{"", bcstag.Tag{MaxLen: -1}},
+		{"optional, nocopy", bcstag.Tag{Optional: true, NoCopy: true, MaxLen: -1}},
+		{"-,unknown", bcstag.Tag{Ignore: true, MaxLen: -1}},
+		{"maxlen=0,utf8,ascii", bcstag.Tag{MaxLen: 0, UTF8: true, ASCII: true}},
+		{"min=-1,max=18446744073709551615", bcstag.Tag{MaxLen: -1, Min: "-1", Max: "18446744073709551615"}},
+		{"since=2,until=2", bcstag.Tag{MaxLen: -1, Since: 2, Until: 2}},
+	} {
+		got, err := bcstag.Parse(c.tag)
+		if err != nil {
+			t.Fatalf("%q: %v", c.tag, err)
+		}
+		if got != c.want {
+			t.Fatalf("%q, want: %+v\ngot:  %+v", c.tag, c.want, got)
+		}
+	}
+}
+
+func TestParse_invalid(t *testing.T) {
+	for _, tag := range []string{"optinal", "maxlen=-1", "min=a", "max=1.5", "since=0", "until=x", "since=3,until=2"} {
+		if _, err := bcstag.Parse(tag); err == nil {
+			t.Fatalf("want error for %q", tag)
+		}
+	}
+
+	_, err := bcstag.Parse("optional,compact=1")
+	var unknown *bcstag.UnknownError
+	if !errors.As(err, &unknown) || unknown.Option != "compact=1" {
+		t.Fatalf("want unknown option compact=1, got %v", err)
+	}
+}
+
+func TestInVersion(t *testing.T) {
+	for _, c := range []struct {
+		version, since, until int
+		want                  bool
+	}{
+		{bcstag.LatestVersion, 0, 0, true},
+		{bcstag.LatestVersion, 2, 0, true},
+		{bcstag.LatestVersion, 0, 2, false},
+		{1, 2, 0, false},
+		{2, 2, 3, true},
+		{3, 2, 3, true},
+		{4, 2, 3, false},
+	} {
+		if got := bcstag.InVersion(c.version, c.since, c.until); got != c.want {
+			t.Fatalf("version %d, since %d, until %d, want %t", c.version, c.since, c.until, c.want)
+		}
+	}
+}
+
+func TestBounds(t *testing.T) {
+	if lo, hi, err := bcstag.IntBounds("", "10", 8); err != nil || lo != -128 || hi != 10 {
+		t.Fatalf("want [-128, 10], got [%d, %d], %v", lo, hi, err)
+	}
+	if lo, hi, err := bcstag.UintBounds("1", "", 64); err != nil || lo != 1 || hi != 1<<64-1 {
+		t.Fatalf("want [1, %d], got [%d, %d], %v", uint64(1<<64-1), lo, hi, err)
+	}
+	if _, _, err := bcstag.UintBounds("", "256", 8); err == nil {
+		t.Fatal("want error for 256 as uint8")
+	}
+	if _, _, err := bcstag.IntBounds("-129", "", 8); err == nil {
+		t.Fatal("want error for -129 as int8")
+	}
+}